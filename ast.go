@@ -0,0 +1,512 @@
+package eval
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Span is the range of an expression occupied by a node, as byte offsets from Start up to (but excluding)
+// End.  the span of a parenthesized node includes its parentheses.  nodes which were not parsed from
+// source, such as those produced by a rewrite, may have a zero span.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Node is a node of the abstract syntax tree of an expression, one of *Literal, *Variable, *Call,
+// *NamedArg, *Unary, *Binary or *SubscriptExpr
+type Node interface {
+	// Span returns the source span of the node
+	Span() Span
+	node()
+}
+
+// Literal is a constant value: a string, a float64 number, a bool or a time.Duration.  unquoted strings
+// (`abc`) are string literals.
+type Literal struct {
+	Value any
+	Loc   Span
+}
+
+// Variable is a reference to a variable, Path holds the full path passed to the variable lookup
+// (`.a.b[0].c`)
+type Variable struct {
+	Path string
+	Loc  Span
+}
+
+// Call is a function call, each argument is an expression, a *NamedArg or a spread *Unary
+type Call struct {
+	Name string
+	Args []Node
+	Loc  Span
+}
+
+// NamedArg is a named function argument (`name=value`)
+type NamedArg struct {
+	Name  string
+	Value Node
+	Loc   Span
+}
+
+// Unary applies a prefix operator to an operand.  the only unary operator is the spread prefix (`...`),
+// which may only appear as a function argument.
+type Unary struct {
+	Op  string
+	X   Node
+	Loc Span
+}
+
+// Binary applies an operator to a pair of operands, OpPos is the byte offset of the operator
+type Binary struct {
+	Op    string
+	X     Node
+	Y     Node
+	OpPos int
+	Loc   Span
+}
+
+// SubscriptExpr selects from the value of an expression, Path holds one or more keys and indexes
+// (`.name[0]`)
+type SubscriptExpr struct {
+	X    Node
+	Path string
+	Loc  Span
+}
+
+func (n *Literal) Span() Span       { return n.Loc }
+func (n *Variable) Span() Span      { return n.Loc }
+func (n *Call) Span() Span          { return n.Loc }
+func (n *NamedArg) Span() Span      { return n.Loc }
+func (n *Unary) Span() Span         { return n.Loc }
+func (n *Binary) Span() Span        { return n.Loc }
+func (n *SubscriptExpr) Span() Span { return n.Loc }
+
+func (*Literal) node()       {}
+func (*Variable) node()      {}
+func (*Call) node()          {}
+func (*NamedArg) node()      {}
+func (*Unary) node()         {}
+func (*Binary) node()        {}
+func (*SubscriptExpr) node() {}
+
+// Parse parses an expression into its abstract syntax tree, recognizing only the builtin operators.  an
+// empty expression yields a nil node.
+func Parse(expression string) (Node, error) {
+	program, err := builtinRegistry.parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	return program.AST()
+}
+
+// AST returns the abstract syntax tree of the program.  a new tree is returned by each call, so it may be
+// modified freely.
+func (p *Program) AST() (Node, error) {
+	if len(p.root.Tokens) == 0 {
+		return nil, nil
+	}
+	return p.root.node()
+}
+
+// NewProgram returns a program evaluating the syntax tree, recognizing only the builtin operators.  as
+// with Compile, constant subexpressions are folded.
+func NewProgram(node Node) (*Program, error) {
+	return builtinRegistry.NewProgram(node)
+}
+
+// NewProgram returns a program evaluating the syntax tree, which may only use the operators held by the
+// registry.  the tree must be expressible as source, so string literals may not contain both single and
+// double quotes.
+func (r *OperatorRegistry) NewProgram(node Node) (*Program, error) {
+	root := &Token{
+		Type: TokenTypeGroup,
+	}
+	if node != nil {
+		t, err := r.token(node)
+		if err != nil {
+			return nil, err
+		}
+		root.Tokens = []*Token{t}
+	}
+
+	expression, err := r.format(node)
+	if err != nil {
+		return nil, err
+	}
+
+	program := &Program{
+		expression: expression,
+		root:       root,
+		operators:  r,
+	}
+	program.fold(nil)
+	return program, nil
+}
+
+// Visitor visits the nodes of a syntax tree, for each node encountered by Walk the visitor returned by
+// Visit is used to visit the node's children, unless it is nil
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a syntax tree depth first, in the order in which the nodes appear in the expression.  it
+// starts by calling v.Visit(node), and if the returned visitor w is not nil, walks each child of the node
+// with w, followed by a call of w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	for _, child := range children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a syntax tree depth first, calling f(node) for each node.  the children of a node are
+// visited if f returns true, followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite returns a copy of the syntax tree in which every node has been replaced by the result of f.  the
+// tree is rewritten bottom up, f receiving a copy of each node whose children have already been rewritten,
+// and may return it unchanged, modify it or return a different node.  the original tree is not modified.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Literal:
+		c := *n
+		return f(&c)
+	case *Variable:
+		c := *n
+		return f(&c)
+	case *Call:
+		c := *n
+		c.Args = make([]Node, len(n.Args))
+		for i, arg := range n.Args {
+			c.Args[i] = Rewrite(arg, f)
+		}
+		return f(&c)
+	case *NamedArg:
+		c := *n
+		c.Value = Rewrite(n.Value, f)
+		return f(&c)
+	case *Unary:
+		c := *n
+		c.X = Rewrite(n.X, f)
+		return f(&c)
+	case *Binary:
+		c := *n
+		c.X = Rewrite(n.X, f)
+		c.Y = Rewrite(n.Y, f)
+		return f(&c)
+	case *SubscriptExpr:
+		c := *n
+		c.X = Rewrite(n.X, f)
+		return f(&c)
+	default:
+		return node
+	}
+}
+
+// children returns the child nodes of a node, in the order in which they appear
+func children(node Node) []Node {
+	switch n := node.(type) {
+	case *Call:
+		return n.Args
+	case *NamedArg:
+		return []Node{n.Value}
+	case *Unary:
+		return []Node{n.X}
+	case *Binary:
+		return []Node{n.X, n.Y}
+	case *SubscriptExpr:
+		return []Node{n.X}
+	default:
+		return nil
+	}
+}
+
+// node converts a token into a syntax tree node
+func (t *Token) node() (Node, error) {
+	var n Node
+
+	switch t.Type {
+	case TokenTypeString:
+		// the token begins at the opening quotation mark
+		n = &Literal{Value: t.Text, Loc: Span{Start: t.Pos, End: t.Pos + len(t.Text) + 2}}
+	case TokenTypeInferredString:
+		n = &Literal{Value: t.Text, Loc: t.textSpan()}
+	case TokenTypeNumber:
+		fl, _ := strconv.ParseFloat(t.Text, 64)
+		n = &Literal{Value: fl, Loc: t.textSpan()}
+	case TokenTypeBoolean:
+		n = &Literal{Value: t.Text == "true", Loc: t.textSpan()}
+	case TokenTypeDuration:
+		d, _ := time.ParseDuration(t.Text)
+		n = &Literal{Value: d, Loc: t.textSpan()}
+	case TokenTypeVariable:
+		n = &Variable{Path: t.Text, Loc: t.textSpan()}
+	case TokenTypeFunction:
+		call := &Call{Name: t.Text, Loc: t.span}
+		for _, arg := range t.Tokens {
+			a, err := arg.argumentNode()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, a)
+		}
+		n = call
+	case TokenTypeOperator:
+		return nil, &PositionError{Pos: t.Pos, Err: fmt.Errorf("bad expression, operator %s is missing an operand", t.Text)}
+	case TokenTypeSeparator:
+		return nil, &PositionError{Pos: t.Pos, Err: fmt.Errorf("bad expression, argument separator outside of function call")}
+	case TokenTypeAssignment:
+		return nil, &PositionError{Pos: t.Pos, Err: fmt.Errorf("bad expression, named argument outside of function call")}
+	case TokenTypeSpread:
+		return nil, &PositionError{Pos: t.Pos, Err: fmt.Errorf("bad expression, spread outside of function call")}
+	default:
+		var err error
+		n, err = groupNode(t.Tokens)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if t.span != (Span{}) {
+		// the span of a parenthesized node is widened to its parentheses
+		setSpan(n, t.span)
+	}
+
+	if t.Subscript != "" {
+		n = &SubscriptExpr{
+			X:    n,
+			Path: t.Subscript,
+			Loc:  Span{Start: n.Span().Start, End: t.subscriptEnd},
+		}
+	}
+	return n, nil
+}
+
+// argumentNode converts a function argument token into a node, wrapping named and spread arguments
+func (t *Token) argumentNode() (Node, error) {
+	value, err := groupNode(t.Tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := Span{Start: t.Pos, End: value.Span().End}
+	switch {
+	case t.Name != "":
+		return &NamedArg{Name: t.Name, Value: value, Loc: loc}, nil
+	case t.Spread:
+		return &Unary{Op: SpreadPrefix, X: value, Loc: loc}, nil
+	default:
+		return value, nil
+	}
+}
+
+// groupNode converts the tokens of a group into a node, applying any operators from left to right
+func groupNode(tokens []*Token) (Node, error) {
+	var result Node
+	var operator *Token
+
+	for _, t := range tokens {
+		if t.Type == TokenTypeOperator {
+			if result == nil || operator != nil {
+				return nil, &PositionError{Pos: t.Pos, Err: fmt.Errorf("bad expression, multiple adjacent operators")}
+			}
+			operator = t
+			continue
+		}
+
+		n, err := t.node()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case result == nil:
+			result = n
+		case operator == nil:
+			return nil, &PositionError{Pos: t.Pos, Err: fmt.Errorf("bad expression, values must be separated by operators")}
+		default:
+			result = &Binary{
+				Op:    operator.Text,
+				X:     result,
+				Y:     n,
+				OpPos: operator.Pos,
+				Loc:   Span{Start: result.Span().Start, End: n.Span().End},
+			}
+			operator = nil
+		}
+	}
+
+	switch {
+	case operator != nil:
+		return nil, &PositionError{Pos: operator.Pos, Err: fmt.Errorf("bad expression, operator %s is missing an operand", operator.Text)}
+	case result == nil:
+		return nil, fmt.Errorf("bad expression, empty group")
+	}
+	return result, nil
+}
+
+// textSpan returns the span of a token consisting only of its text
+func (t *Token) textSpan() Span {
+	return Span{Start: t.Pos, End: t.Pos + len(t.Text)}
+}
+
+func setSpan(node Node, span Span) {
+	switch n := node.(type) {
+	case *Literal:
+		n.Loc = span
+	case *Variable:
+		n.Loc = span
+	case *Call:
+		n.Loc = span
+	case *NamedArg:
+		n.Loc = span
+	case *Unary:
+		n.Loc = span
+	case *Binary:
+		n.Loc = span
+	case *SubscriptExpr:
+		n.Loc = span
+	}
+}
+
+// isVariablePath reports whether path tokenizes to exactly one variable, so that a program built from it
+// formats to an expression which parses back to the same variable
+func (r *OperatorRegistry) isVariablePath(path string) bool {
+	if !variableFinder.MatchString(path) {
+		return false
+	}
+	root, err := r.tokenize(path)
+	if err != nil || len(root.Tokens) != 1 {
+		return false
+	}
+	t := root.Tokens[0]
+	return t.Type == TokenTypeVariable && t.Text == path && len(t.Subscript) == 0
+}
+
+// token converts a syntax tree node into the token evaluated by a program
+func (r *OperatorRegistry) token(node Node) (*Token, error) {
+	switch n := node.(type) {
+	case *Literal:
+		return literalToken(n)
+	case *Variable:
+		if !r.isVariablePath(n.Path) {
+			return nil, fmt.Errorf("invalid variable path %q", n.Path)
+		}
+		return &Token{Type: TokenTypeVariable, Text: n.Path, Pos: n.Loc.Start, span: n.Loc}, nil
+	case *Call:
+		if n.Name == "" {
+			return nil, fmt.Errorf("function name must not be empty")
+		}
+		t := &Token{Type: TokenTypeFunction, Text: n.Name, Pos: n.Loc.Start, span: n.Loc}
+		named := false
+		for _, arg := range n.Args {
+			argToken := &Token{Type: TokenTypeGroup, Pos: arg.Span().Start}
+			value := arg
+			switch a := arg.(type) {
+			case *NamedArg:
+				if a.Name == "" {
+					return nil, fmt.Errorf("named argument in call to %s has no name", n.Name)
+				}
+				argToken.Name = a.Name
+				value = a.Value
+				named = true
+			case *Unary:
+				if a.Op != SpreadPrefix {
+					return nil, fmt.Errorf("unknown unary operator %s", a.Op)
+				}
+				argToken.Spread = true
+				value = a.X
+			}
+			if named && argToken.Name == "" {
+				return nil, fmt.Errorf("positional argument follows named argument in call to %s", n.Name)
+			}
+
+			v, err := r.token(value)
+			if err != nil {
+				return nil, err
+			}
+			argToken.Tokens = []*Token{v}
+			t.Tokens = append(t.Tokens, argToken)
+		}
+		return t, nil
+	case *NamedArg:
+		return nil, fmt.Errorf("named argument %s outside of function call", n.Name)
+	case *Unary:
+		return nil, fmt.Errorf("unary operator %s outside of function call", n.Op)
+	case *Binary:
+		if _, ok := r.Lookup(n.Op); !ok {
+			return nil, fmt.Errorf("unknown operator %s", n.Op)
+		}
+		x, err := r.token(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := r.token(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &Token{
+			Type:   TokenTypeGroup,
+			Tokens: []*Token{x, {Type: TokenTypeOperator, Text: n.Op, Pos: n.OpPos}, y},
+			Pos:    n.Loc.Start,
+			span:   n.Loc,
+		}, nil
+	case *SubscriptExpr:
+		if !subscriptParser.MatchString(n.Path) {
+			return nil, fmt.Errorf("invalid subscript %s", n.Path)
+		}
+		x, err := r.token(n.X)
+		if err != nil {
+			return nil, err
+		}
+		if x.Subscript != "" || x.Name != "" || x.Spread {
+			// wrap the token so that the subscripts apply in turn
+			x = &Token{Type: TokenTypeGroup, Tokens: []*Token{x}, Pos: x.Pos}
+		}
+		x.Subscript = n.Path
+		x.subscriptEnd = n.Loc.End
+		return x, nil
+	case nil:
+		return nil, fmt.Errorf("missing node")
+	default:
+		return nil, fmt.Errorf("unknown node type %T", node)
+	}
+}
+
+func literalToken(n *Literal) (*Token, error) {
+	t := &Token{Pos: n.Loc.Start, span: n.Loc}
+	switch v := CastToFloat64IfApplicable(n.Value).(type) {
+	case string:
+		t.Type = TokenTypeString
+		t.Text = v
+	case float64:
+		t.Type = TokenTypeNumber
+		t.Text = strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		t.Type = TokenTypeBoolean
+		t.Text = strconv.FormatBool(v)
+	case time.Duration:
+		t.Type = TokenTypeDuration
+		t.Text = v.String()
+	default:
+		return nil, fmt.Errorf("unsupported literal %v of type %T", n.Value, n.Value)
+	}
+	return t, nil
+}
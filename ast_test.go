@@ -0,0 +1,195 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	//                    0         1         2         3         4         5
+	//                    012345678901234567890123456789012345678901234567890123456789
+	node, err := Parse("f(.a, ....b, n= 'x').yy > (1 + 2) * 3 || abc == 5m")
+	assert.NoError(t, err)
+	assert.Equal(t, &Binary{
+		Op: "||",
+		X: &Binary{
+			Op: ">",
+			X: &SubscriptExpr{
+				X: &Call{
+					Name: "f",
+					Args: []Node{
+						&Variable{Path: ".a", Loc: Span{Start: 2, End: 4}},
+						&Unary{Op: "...", X: &Variable{Path: ".b", Loc: Span{Start: 9, End: 11}}, Loc: Span{Start: 6, End: 11}},
+						&NamedArg{Name: "n", Value: &Literal{Value: "x", Loc: Span{Start: 16, End: 19}}, Loc: Span{Start: 13, End: 19}},
+					},
+					Loc: Span{Start: 0, End: 20},
+				},
+				Path: ".yy",
+				Loc:  Span{Start: 0, End: 23},
+			},
+			Y: &Binary{
+				Op: "*",
+				X: &Binary{
+					Op:    "+",
+					X:     &Literal{Value: float64(1), Loc: Span{Start: 27, End: 28}},
+					Y:     &Literal{Value: float64(2), Loc: Span{Start: 31, End: 32}},
+					OpPos: 29,
+					Loc:   Span{Start: 26, End: 33},
+				},
+				Y:     &Literal{Value: float64(3), Loc: Span{Start: 36, End: 37}},
+				OpPos: 34,
+				Loc:   Span{Start: 26, End: 37},
+			},
+			OpPos: 24,
+			Loc:   Span{Start: 0, End: 37},
+		},
+		Y: &Binary{
+			Op:    "==",
+			X:     &Literal{Value: "abc", Loc: Span{Start: 41, End: 44}},
+			Y:     &Literal{Value: 5 * time.Minute, Loc: Span{Start: 48, End: 50}},
+			OpPos: 45,
+			Loc:   Span{Start: 41, End: 50},
+		},
+		OpPos: 38,
+		Loc:   Span{Start: 0, End: 50},
+	}, node)
+}
+
+func TestParseEmpty(t *testing.T) {
+	node, err := Parse("")
+	assert.NoError(t, err)
+	assert.Nil(t, node)
+}
+
+func TestParseError(t *testing.T) {
+	for _, expression := range []string{"1 2", "== 1", "1 ==", "1 == == 2", "a, b", "(1 + 2"} {
+		_, err := Parse(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	node, err := Parse("len(.items[0].name) > 2 * .min")
+	assert.NoError(t, err)
+
+	var visited []string
+	Inspect(node, func(n Node) bool {
+		if n != nil {
+			visited = append(visited, fmt.Sprintf("%T", n))
+		}
+		return true
+	})
+	assert.Equal(t, []string{"*eval.Binary", "*eval.Call", "*eval.Variable", "*eval.Binary", "*eval.Literal", "*eval.Variable"}, visited)
+
+	visited = nil
+	Inspect(node, func(n Node) bool {
+		if n != nil {
+			visited = append(visited, fmt.Sprintf("%T", n))
+		}
+		_, isCall := n.(*Call)
+		return !isCall
+	})
+	assert.Equal(t, []string{"*eval.Binary", "*eval.Call", "*eval.Binary", "*eval.Literal", "*eval.Variable"}, visited)
+}
+
+func TestRewrite(t *testing.T) {
+	node, err := Parse(".a + .b * 2")
+	assert.NoError(t, err)
+
+	rewritten := Rewrite(node, func(n Node) Node {
+		if v, ok := n.(*Variable); ok {
+			v.Path = ".vars" + v.Path
+		}
+		return n
+	})
+
+	program, err := NewProgram(rewritten)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".vars.a", ".vars.b"}, program.Variables())
+
+	result, err := program.Evaluate(func(key string) (any, error) {
+		return float64(len(key)), nil
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(7+7*2), result)
+
+	// the original tree is unmodified
+	assert.Equal(t, ".a", node.(*Binary).X.(*Variable).Path)
+}
+
+func TestNewProgramRoundTrip(t *testing.T) {
+	for _, expression := range []string{
+		"f(.a, ....b, n = 'x').yy > (1 + 2) * 3 || abc == 5m",
+		"2 ** 3 ** 2 - 1",
+		"('abc').xy[0]",
+		"g()",
+		".x",
+	} {
+		node, err := Parse(expression)
+		assert.NoError(t, err)
+
+		program, err := NewProgram(node)
+		assert.NoError(t, err)
+		roundTripped, err := program.AST()
+		assert.NoError(t, err)
+		assert.Equal(t, node, roundTripped, expression)
+	}
+}
+
+func TestNewProgramEvaluate(t *testing.T) {
+	node := &SubscriptExpr{
+		X: &Call{
+			Name: "pair",
+			Args: []Node{
+				&Binary{Op: "+", X: &Literal{Value: "a"}, Y: &Literal{Value: "b"}},
+				&NamedArg{Name: "second", Value: &Literal{Value: 2}},
+			},
+		},
+		Path: "[1]",
+	}
+	program, err := NewProgram(node)
+	assert.NoError(t, err)
+
+	result, err := program.EvaluateNamed(nil, func(name string, args []any, namedArgs map[string]any) (any, error) {
+		return []any{args[0], namedArgs["second"]}, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), result)
+}
+
+func TestNewProgramError(t *testing.T) {
+	for _, node := range []Node{
+		&Binary{Op: "in", X: &Literal{Value: 1.0}, Y: &Literal{Value: 2.0}},
+		&Literal{Value: []any{1}},
+		&Variable{Path: "a"},
+		&Variable{Path: ".a b"},
+		&Variable{Path: ".a*2"},
+		&SubscriptExpr{X: &Variable{Path: ".a"}, Path: "b"},
+		&NamedArg{Name: "a", Value: &Literal{Value: 1.0}},
+		&Call{Name: "f", Args: []Node{&NamedArg{Name: "a", Value: &Literal{Value: 1.0}}, &Literal{Value: 1.0}}},
+		&Binary{Op: "+", X: &Literal{Value: 1.0}},
+	} {
+		_, err := NewProgram(node)
+		assert.Error(t, err, fmt.Sprintf("%#v", node))
+	}
+
+	for _, path := range []string{".a", ".a.bc[0]", ".a_1"} {
+		program, err := NewProgram(&Variable{Path: path})
+		assert.NoError(t, err, path)
+		assert.Equal(t, path, program.String())
+	}
+
+	registry := NewOperatorRegistry()
+	assert.NoError(t, registry.Register(Operator{Symbol: "in", Precedence: PrecedenceComparison, Func: func(a, b any) (any, error) {
+		return strings.Contains(b.(string), a.(string)), nil
+	}}))
+	program, err := registry.NewProgram(&Binary{Op: "in", X: &Literal{Value: "b"}, Y: &Literal{Value: "abc"}})
+	assert.NoError(t, err)
+	result, err := program.Evaluate(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+}
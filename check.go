@@ -0,0 +1,424 @@
+package eval
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Type is the static type of an expression value, as understood by the type checker
+type Type string
+
+const (
+	// TypeAny is a value whose type is not known until evaluation, it is compatible with every other type
+	TypeAny      Type = "any"
+	TypeString   Type = "string"
+	TypeNumber   Type = "number"
+	TypeBoolean  Type = "boolean"
+	TypeArray    Type = "array"
+	TypeMapping  Type = "mapping"
+	TypeTime     Type = "time"
+	TypeDuration Type = "duration"
+)
+
+// Signature declares the parameter and return types of a function
+type Signature struct {
+	Params []Type
+	// Variadic is true if the last parameter may be supplied any number of times (including none), which
+	// requires at least one parameter
+	Variadic bool
+	// Named holds the types of the named arguments accepted by the function
+	Named   map[string]Type
+	Returns Type
+}
+
+// Schema declares the types of the variables and functions available to an expression.  variables are
+// keyed by path as written in expressions (`.spec.replicas`, `.items[0]`), subscripts of a variable which
+// is declared as a mapping, array or any are permitted and have type any.
+type Schema struct {
+	Variables map[string]Type
+	Functions map[string]Signature
+}
+
+// Check compiles an expression with the builtin operators and type checks it against the schema
+func Check(expression string, schema *Schema) (Type, error) {
+	program, err := builtinRegistry.parse(expression)
+	if err != nil {
+		return TypeAny, err
+	}
+	return program.Check(schema)
+}
+
+// Check infers the type of every node of the program from the types declared by the schema, without
+// evaluating it, and returns the type of the result.  every type error found is reported as a
+// *PositionError, joined in order of position.  operators other than the builtins produce values of type
+// any.
+func (p *Program) Check(schema *Schema) (Type, error) {
+	if schema == nil {
+		schema = &Schema{}
+	}
+
+	c := &checker{
+		schema:    schema,
+		operators: p.operators,
+	}
+	result := c.check(p.root)
+	if len(c.errs) == 0 {
+		return result, nil
+	}
+
+	slices.SortStableFunc(c.errs, func(a, b *PositionError) int {
+		return cmp.Compare(a.Pos, b.Pos)
+	})
+	errs := make([]error, len(c.errs))
+	for i, err := range c.errs {
+		errs[i] = err
+	}
+	return result, errors.Join(errs...)
+}
+
+// checker holds the state of a single type check
+type checker struct {
+	schema    *Schema
+	operators *OperatorRegistry
+	errs      []*PositionError
+}
+
+// errorf records a type error, the node at fault is then treated as any so that errors do not cascade
+func (c *checker) errorf(pos int, format string, a ...any) Type {
+	c.errs = append(c.errs, &PositionError{Pos: pos, Err: fmt.Errorf(format, a...)})
+	return TypeAny
+}
+
+// check infers the type of a token, mirroring the order in which evaluate visits it
+func (c *checker) check(t *Token) Type {
+	var result Type
+
+	switch t.Type {
+	case TokenTypeFunction:
+		result = c.checkCall(t)
+	case TokenTypeString, TokenTypeInferredString:
+		result = TypeString
+	case TokenTypeNumber:
+		result = TypeNumber
+	case TokenTypeBoolean:
+		result = TypeBoolean
+	case TokenTypeDuration:
+		result = TypeDuration
+	case TokenTypeVariable:
+		return c.checkVariable(t)
+	case TokenTypeSeparator:
+		return c.errorf(t.Pos, "argument separator outside of function call")
+	case TokenTypeAssignment:
+		return c.errorf(t.Pos, "named argument outside of function call")
+	case TokenTypeSpread:
+		return c.errorf(t.Pos, "spread outside of function call")
+	default:
+		result = c.checkGroup(t)
+	}
+
+	if t.Subscript != "" {
+		return c.subscript(t.Pos, result, t.Subscript)
+	}
+	return result
+}
+
+// checkGroup applies the operators of a group from left to right
+func (c *checker) checkGroup(t *Token) Type {
+	var result Type
+	var operator *Token
+	prevOperator := true
+
+	for _, token := range t.Tokens {
+		if token.Type == TokenTypeOperator {
+			if prevOperator {
+				return c.errorf(token.Pos, "multiple adjacent operators")
+			}
+			operator = token
+			prevOperator = true
+			continue
+		}
+
+		value := c.check(token)
+		switch {
+		case result == "":
+			result = value
+		case !prevOperator:
+			return c.errorf(token.Pos, "values must be separated by operators")
+		default:
+			result = c.checkOperator(operator, result, value)
+		}
+		prevOperator = false
+	}
+
+	if result == "" {
+		return TypeAny
+	}
+	return result
+}
+
+// checkOperator returns the result type of a builtin operator applied to operands of the given types
+func (c *checker) checkOperator(operator *Token, a Type, b Type) Type {
+	if _, ok := c.operators.Lookup(operator.Text); !ok {
+		return c.errorf(operator.Pos, "unknown operator %s", operator.Text)
+	}
+
+	switch operator.Text {
+	case OperatorEquals, OperatorUnequals:
+		if !equatable(a, b) {
+			return c.errorf(operator.Pos, "%s and %s are incompatible types for %s comparison", a, b, operator.Text)
+		}
+		return TypeBoolean
+	case OperatorGreater, OperatorGreaterEquals, OperatorLess, OperatorLessEquals:
+		if !ordered(a, b) {
+			return c.errorf(operator.Pos, "%s and %s are incompatible types for comparison", a, b)
+		}
+		return TypeBoolean
+	case OperatorAnd, OperatorOr:
+		// the result is one of the operands
+		if a == b {
+			return a
+		}
+		return TypeAny
+	case OperatorPlus:
+		return c.arithmetic(operator, a, b, "addition/concatenation", map[[2]Type]Type{
+			{TypeString, TypeString}:     TypeString,
+			{TypeNumber, TypeNumber}:     TypeNumber,
+			{TypeArray, TypeArray}:       TypeArray,
+			{TypeTime, TypeDuration}:     TypeTime,
+			{TypeDuration, TypeDuration}: TypeDuration,
+			{TypeDuration, TypeTime}:     TypeTime,
+		})
+	case OperatorMinus:
+		return c.arithmetic(operator, a, b, "subtraction", map[[2]Type]Type{
+			{TypeNumber, TypeNumber}:     TypeNumber,
+			{TypeTime, TypeDuration}:     TypeTime,
+			{TypeTime, TypeTime}:         TypeDuration,
+			{TypeDuration, TypeDuration}: TypeDuration,
+		})
+	case OperatorMultiply:
+		return c.arithmetic(operator, a, b, "multiplication", map[[2]Type]Type{
+			{TypeNumber, TypeNumber}:   TypeNumber,
+			{TypeNumber, TypeDuration}: TypeDuration,
+			{TypeDuration, TypeNumber}: TypeDuration,
+		})
+	case OperatorDivide:
+		return c.arithmetic(operator, a, b, "division", map[[2]Type]Type{
+			{TypeNumber, TypeNumber}:     TypeNumber,
+			{TypeDuration, TypeNumber}:   TypeDuration,
+			{TypeDuration, TypeDuration}: TypeNumber,
+		})
+	case OperatorExponent:
+		return c.arithmetic(operator, a, b, "exponentiation", map[[2]Type]Type{
+			{TypeNumber, TypeNumber}: TypeNumber,
+		})
+	default:
+		return TypeAny
+	}
+}
+
+// arithmetic looks up the result type of an arithmetic operator, a result which depends on an operand of
+// type any is itself any
+func (c *checker) arithmetic(operator *Token, a Type, b Type, description string, results map[[2]Type]Type) Type {
+	if result, ok := results[[2]Type{a, b}]; ok {
+		return result
+	}
+
+	if a == TypeAny || b == TypeAny {
+		for operands := range results {
+			if (a == TypeAny || operands[0] == a) && (b == TypeAny || operands[1] == b) {
+				return TypeAny
+			}
+		}
+	}
+	return c.errorf(operator.Pos, "%s and %s are incompatible types for %s", a, b, description)
+}
+
+// equatable reports whether values of the types may be compared using == and !=
+func equatable(a Type, b Type) bool {
+	switch {
+	case a == TypeAny || b == TypeAny || a == b:
+		return true
+	default:
+		// times may be compared against RFC 3339 formatted strings
+		return (a == TypeTime && b == TypeString) || (a == TypeString && b == TypeTime)
+	}
+}
+
+// ordered reports whether values of the types may be ordered using <, <=, > and >=
+func ordered(a Type, b Type) bool {
+	return a != TypeMapping && b != TypeMapping && equatable(a, b)
+}
+
+// checkVariable returns the declared type of a variable, including any subscript
+func (c *checker) checkVariable(t *Token) Type {
+	path := t.Text + t.Subscript
+	if typ, ok := c.schema.Variables[path]; ok {
+		return typ
+	}
+
+	// find the longest declared path of which this variable is a subscript
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i] != '.' && path[i] != '[' {
+			continue
+		}
+		if typ, ok := c.schema.Variables[path[:i]]; ok {
+			return c.subscript(t.Pos, typ, path[i:])
+		}
+	}
+	return c.errorf(t.Pos, "variable %s is not declared", path)
+}
+
+// subscript returns the type of a value of the given type once subscripted
+func (c *checker) subscript(pos int, typ Type, subscript string) Type {
+	switch {
+	case typ == TypeAny, typ == TypeMapping, typ == TypeArray:
+		return TypeAny
+	case typ == TypeString && strings.HasPrefix(subscript, "[") && strings.Index(subscript, "]") == len(subscript)-1:
+		// indexing a string yields the value of the byte at that index
+		return TypeNumber
+	default:
+		return c.errorf(pos, "cannot subscript %s with %s", typ, subscript)
+	}
+}
+
+// checkCall checks the arguments of a function call against its signature, returning its result type
+func (c *checker) checkCall(t *Token) Type {
+	var positional []*Token
+	var named []*Token
+	spread := false
+	for _, arg := range t.Tokens {
+		switch {
+		case arg.Name != "":
+			named = append(named, arg)
+		case len(named) > 0:
+			c.errorf(arg.Pos, "positional argument follows named argument in call to %s", t.Text)
+		default:
+			positional = append(positional, arg)
+			spread = spread || arg.Spread
+		}
+	}
+
+	sig, ok := c.schema.Functions[t.Text]
+	if !ok {
+		for _, arg := range t.Tokens {
+			c.check(arg)
+		}
+		return c.errorf(t.Pos, "function %s is not declared", t.Text)
+	}
+
+	if sig.Variadic && len(sig.Params) == 0 {
+		for _, arg := range t.Tokens {
+			c.check(arg)
+		}
+		return c.errorf(t.Pos, "function %s is declared variadic without any parameters", t.Text)
+	}
+
+	required := len(sig.Params)
+	if sig.Variadic {
+		required--
+	}
+	switch {
+	case spread:
+		// the number of arguments is only known once the spread array is evaluated
+	case sig.Variadic && len(positional) < required:
+		c.errorf(t.Pos, "function %s expects at least %d argument(s), got %d", t.Text, required, len(positional))
+	case !sig.Variadic && len(positional) != required:
+		c.errorf(t.Pos, "function %s expects %d argument(s), got %d", t.Text, required, len(positional))
+	}
+
+	for i, arg := range positional {
+		typ := c.check(arg)
+		if arg.Spread {
+			if typ != TypeAny && typ != TypeArray {
+				c.errorf(arg.Pos, "cannot spread %s into arguments of %s, only arrays can be spread", typ, t.Text)
+			}
+			// the positions of any subsequent arguments are unknown
+			for _, arg := range positional[i+1:] {
+				c.check(arg)
+			}
+			break
+		}
+
+		var param Type
+		switch {
+		case i < required:
+			param = sig.Params[i]
+		case sig.Variadic:
+			param = sig.Params[required]
+		default:
+			continue
+		}
+		if !assignable(typ, param) {
+			c.errorf(arg.Pos, "function %s argument %d must be a %s, got %s", t.Text, i+1, param, typ)
+		}
+	}
+
+	seen := map[string]struct{}{}
+	for _, arg := range named {
+		typ := c.check(arg)
+		if _, ok := seen[arg.Name]; ok {
+			c.errorf(arg.Pos, "named argument %s supplied more than once in call to %s", arg.Name, t.Text)
+			continue
+		}
+		seen[arg.Name] = struct{}{}
+
+		param, ok := sig.Named[arg.Name]
+		if !ok {
+			c.errorf(arg.Pos, "function %s has no named argument %s", t.Text, arg.Name)
+			continue
+		}
+		if !assignable(typ, param) {
+			c.errorf(arg.Pos, "function %s argument %s must be a %s, got %s", t.Text, arg.Name, param, typ)
+		}
+	}
+
+	if sig.Returns == "" {
+		return TypeAny
+	}
+	return sig.Returns
+}
+
+// assignable reports whether a value of type typ may be passed to a parameter of type param
+func assignable(typ Type, param Type) bool {
+	return typ == param || typ == TypeAny || param == TypeAny || param == ""
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// TypeOf returns the type checker's type for a Go type, as received by or returned from a function
+func TypeOf(t reflect.Type) Type {
+	switch {
+	case t == timeType:
+		return TypeTime
+	case t == durationType:
+		return TypeDuration
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return TypeString
+	case reflect.Bool:
+		return TypeBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return TypeNumber
+	case reflect.Slice, reflect.Array:
+		return TypeArray
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return TypeMapping
+		}
+		return TypeAny
+	default:
+		return TypeAny
+	}
+}
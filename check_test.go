@@ -0,0 +1,160 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSchema = &Schema{
+	Variables: map[string]Type{
+		".replicas": TypeNumber,
+		".name":     TypeString,
+		".enabled":  TypeBoolean,
+		".created":  TypeTime,
+		".timeout":  TypeDuration,
+		".labels":   TypeMapping,
+		".items":    TypeArray,
+		".data":     TypeAny,
+		".spec":     TypeMapping,
+		".spec.min": TypeNumber,
+	},
+	Functions: map[string]Signature{
+		"now":    {Returns: TypeTime},
+		"len":    {Params: []Type{TypeAny}, Returns: TypeNumber},
+		"concat": {Params: []Type{TypeString, TypeString}, Variadic: true, Returns: TypeString},
+		"fetch":  {Params: []Type{TypeString}, Named: map[string]Type{"timeout": TypeDuration}, Returns: TypeMapping},
+		"parse":  {Params: []Type{TypeString}},
+		"broken": {Variadic: true},
+	},
+}
+
+func TestCheck(t *testing.T) {
+	tests := map[string]Type{
+		".replicas":                          TypeNumber,
+		".replicas * 2 + 1":                  TypeNumber,
+		".replicas == 3":                     TypeBoolean,
+		".replicas > 3 && .enabled":          TypeBoolean,
+		".replicas > 3 && .name":             TypeAny,
+		"'abc' + .name":                      TypeString,
+		"now() - .created":                   TypeDuration,
+		"now() - 5m > .created":              TypeBoolean,
+		".timeout * 2":                       TypeDuration,
+		".timeout / 5s":                      TypeNumber,
+		".created == '2024-01-01T00:00:00Z'": TypeBoolean,
+		".items + .items":                    TypeArray,
+		".labels.app":                        TypeAny,
+		".items[0].name":                     TypeAny,
+		".spec.min + 1":                      TypeNumber,
+		".data.x.y + 1":                      TypeAny,
+		".data + 1":                          TypeAny,
+		"len(.items) > 0":                    TypeBoolean,
+		"concat('a')":                        TypeString,
+		"concat('a', .name, 'c')":            TypeString,
+		"concat(....items)":                  TypeString,
+		"fetch(.name, timeout=5s).status":    TypeAny,
+		"parse(.name)":                       TypeAny,
+		"(.replicas + 1) * 2 ** 2":           TypeNumber,
+		"abc":                                TypeString,
+		"true || false":                      TypeBoolean,
+		"'abc'[0]":                           TypeNumber,
+		".name[1] + 1":                       TypeNumber,
+	}
+
+	for expression, expected := range tests {
+		typ, err := Check(expression, testSchema)
+		if assert.NoError(t, err, expression) {
+			assert.Equal(t, expected, typ, expression)
+		}
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := map[string][]int{
+		".replicas == 'three'":              {10},
+		".replicas + .name":                 {10},
+		".name > 5":                         {6},
+		".labels < .labels":                 {8},
+		".timeout * .timeout":               {9},
+		"now() + now()":                     {6},
+		".missing + 1":                      {0},
+		".replicas.x":                       {0},
+		"undeclared(1)":                     {0},
+		"len()":                             {0},
+		"len(1, 2)":                         {0},
+		"concat(1)":                         {7},
+		"concat('a', 'b', 3)":               {17},
+		"concat(...'replicas')":             {7},
+		"fetch(.name, timeout=5)":           {13},
+		"fetch(.name, retries=5)":           {13},
+		".replicas == 'a' || .name + 1 > 2": {10, 26},
+		"(.name + 1).xy":                    {7},
+		"len(.name).xy":                     {0},
+		"broken(1)":                         {0},
+		"'abc'.xy":                          {0},
+		"('abc')[0][1]":                     {1},
+	}
+
+	for expression, positions := range tests {
+		_, err := Check(expression, testSchema)
+		if !assert.Error(t, err, expression) {
+			continue
+		}
+
+		var joined interface{ Unwrap() []error }
+		if !assert.True(t, errors.As(err, &joined), expression) {
+			continue
+		}
+		var actual []int
+		for _, err := range joined.Unwrap() {
+			var posErr *PositionError
+			if assert.True(t, errors.As(err, &posErr), expression) {
+				actual = append(actual, posErr.Pos)
+			}
+		}
+		assert.Equal(t, positions, actual, expression)
+	}
+}
+
+func TestCheckMessage(t *testing.T) {
+	_, err := Check(".replicas == 'three'", testSchema)
+	assert.EqualError(t, err, "number and string are incompatible types for == comparison at position 10")
+
+	_, err = Check("broken(1)", testSchema)
+	assert.EqualError(t, err, "function broken is declared variadic without any parameters at position 0")
+}
+
+func TestCheckCustomOperator(t *testing.T) {
+	registry := NewOperatorRegistry()
+	err := registry.Register(Operator{Symbol: "in", Precedence: PrecedenceComparison, Func: func(a, b any) (any, error) {
+		return true, nil
+	}})
+	assert.NoError(t, err)
+
+	program, err := registry.Compile(".name in .items && .enabled")
+	assert.NoError(t, err)
+	typ, err := program.Check(testSchema)
+	assert.NoError(t, err)
+	assert.Equal(t, TypeAny, typ)
+}
+
+func TestFunctionRegistrySignatures(t *testing.T) {
+	registry := NewFunctionRegistry()
+	assert.NoError(t, registry.Register("repeat", func(s string, n int) string { return "" }))
+	assert.NoError(t, registry.Register("sum", func(n ...float64) (float64, error) { return 0, nil }))
+
+	signatures := registry.Signatures()
+	assert.Equal(t, Signature{Params: []Type{TypeString, TypeNumber}, Returns: TypeString}, signatures["repeat"])
+	assert.Equal(t, Signature{Params: []Type{TypeNumber}, Variadic: true, Returns: TypeNumber}, signatures["sum"])
+
+	typ, err := Check("repeat(.name, sum(1, 2, .replicas))", &Schema{
+		Variables: testSchema.Variables,
+		Functions: signatures,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, TypeString, typ)
+
+	_, err = Check("repeat(.replicas, 2)", &Schema{Functions: signatures, Variables: testSchema.Variables})
+	assert.Error(t, err)
+}
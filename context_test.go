@@ -0,0 +1,106 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type contextKey string
+
+func TestEvaluateContextValues(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey("tenant"), "acme")
+
+	vLookup := func(ctx context.Context, key string) (any, error) {
+		return ctx.Value(contextKey("tenant")), nil
+	}
+	fCall := func(ctx context.Context, name string, args []any, namedArgs map[string]any) (any, error) {
+		return ctx.Value(contextKey("tenant")).(string) + "/" + args[0].(string) + "/" + namedArgs["suffix"].(string), nil
+	}
+
+	result, err := EvaluateContext(ctx, "join(.tenant, suffix=x)", vLookup, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme/acme/x", result)
+}
+
+func TestEvaluateContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls []string
+	fCall := func(ctx context.Context, name string, args []any, namedArgs map[string]any) (any, error) {
+		calls = append(calls, name)
+		if name == "slow" {
+			cancel()
+		}
+		return 1., nil
+	}
+
+	_, err := EvaluateContext(ctx, "slow() + after()", nil, fCall)
+	assert.ErrorIs(t, err, context.Canceled)
+	var posErr *PositionError
+	assert.True(t, errors.As(err, &posErr))
+	assert.Equal(t, 9, posErr.Pos)
+	assert.Equal(t, []string{"slow"}, calls)
+
+	_, err = EvaluateContext(ctx, "1 + 1", nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, errors.As(err, &posErr))
+	assert.Equal(t, 0, posErr.Pos)
+}
+
+func TestEvaluateContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	fCall := func(ctx context.Context, name string, args []any, namedArgs map[string]any) (any, error) {
+		<-ctx.Done()
+		return nil, nil
+	}
+
+	program, err := Compile("wait() && 'never'")
+	assert.NoError(t, err)
+	_, err = program.EvaluateContext(ctx, &Env{FunctionsContext: fCall})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestEnvContextCallbacks(t *testing.T) {
+	env := &Env{
+		Variables: func(key string) (any, error) {
+			return "plain", nil
+		},
+	}
+	child := env.NewChild()
+	child.VariablesContext = func(ctx context.Context, key string) (any, error) {
+		return "context", nil
+	}
+
+	result, err := EvaluateWith(".a", env)
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", result)
+
+	result, err = EvaluateWithContext(context.Background(), ".a", child)
+	assert.NoError(t, err)
+	assert.Equal(t, "context", result)
+}
+
+func TestTokenPositions(t *testing.T) {
+	root, err := tokenize(`  .a + fn( "x y", ....b)`)
+	assert.NoError(t, err)
+
+	sum := root.Tokens[0]
+	assert.Equal(t, 2, sum.Pos)
+	assert.Equal(t, 2, sum.Tokens[0].Pos)
+	assert.Equal(t, 5, sum.Tokens[1].Pos)
+
+	fn := sum.Tokens[2]
+	assert.Equal(t, TokenTypeFunction, fn.Type)
+	assert.Equal(t, 7, fn.Pos)
+	assert.Equal(t, 11, fn.Tokens[0].Pos)
+	assert.Equal(t, 11, fn.Tokens[0].Tokens[0].Pos)
+	assert.Equal(t, 18, fn.Tokens[1].Pos)
+	assert.Equal(t, 21, fn.Tokens[1].Tokens[0].Pos)
+}
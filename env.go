@@ -0,0 +1,218 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+)
+
+// Flag identifies a strictness flag or feature toggle held by an Env
+type Flag string
+
+const (
+	// FlagStrictVariables fails evaluation when a variable lookup yields nil, rather than using the nil value
+	FlagStrictVariables Flag = "STRICT_VARIABLES"
+	// FlagStrictStrings rejects unquoted strings (`abc`) at compile time, requiring string literals to be quoted
+	FlagStrictStrings Flag = "STRICT_STRINGS"
+	// FlagNamedArguments permits named function arguments (`fn(timeout=5)`)
+	FlagNamedArguments Flag = "NAMED_ARGUMENTS"
+	// FlagSpreadArguments permits spread function arguments (`fn(...list)`)
+	FlagSpreadArguments Flag = "SPREAD_ARGUMENTS"
+	// FlagDurationLiterals permits duration literals (`5m`), when disabled these are treated as unquoted strings
+	FlagDurationLiterals Flag = "DURATION_LITERALS"
+	// FlagConstantFolding folds constant subexpressions when compiling (`60 * 60` becomes `3600`)
+	FlagConstantFolding Flag = "CONSTANT_FOLDING"
+)
+
+// defaultFlags holds the value of each flag when not set on an environment or any of its parents
+var defaultFlags = map[Flag]bool{
+	FlagStrictVariables:  false,
+	FlagStrictStrings:    false,
+	FlagNamedArguments:   true,
+	FlagSpreadArguments:  true,
+	FlagDurationLiterals: true,
+	FlagConstantFolding:  true,
+}
+
+// Env bundles everything used to compile and evaluate expressions.  any field which is left unset is
+// inherited from the parent environment (see NewChild), the zero value is a usable environment with no
+// parent.
+type Env struct {
+	parent *Env
+	flags  map[Flag]bool
+
+	// Variables resolves variables referenced by expressions.  ignored when VariablesContext is set
+	Variables VariableLookup
+	// VariablesContext resolves variables, receiving the context supplied to the evaluation
+	VariablesContext VariableLookupContext
+	// Functions executes function calls, rejecting named arguments.  ignored when NamedFunctions or
+	// FunctionsContext is set
+	Functions FunctionCall
+	// NamedFunctions executes function calls, receiving any named arguments.  ignored when FunctionsContext
+	// is set
+	NamedFunctions NamedFunctionCall
+	// FunctionsContext executes function calls, receiving the context supplied to the evaluation and any
+	// named arguments
+	FunctionsContext FunctionCallContext
+	// Operators is the registry used when compiling expressions, the builtin operators are used if unset
+	Operators *OperatorRegistry
+	// Limits bounds the resources used by compilation and evaluation, unlimited if unset
+	Limits *Limits
+}
+
+// NewEnv returns an empty environment, using the builtin operators and default flags
+func NewEnv() *Env {
+	return &Env{}
+}
+
+// NewChild returns an environment which inherits all entries from this one, any of which can be overridden
+// on the child without affecting the parent
+func (e *Env) NewChild() *Env {
+	return &Env{
+		parent: e,
+	}
+}
+
+// SetFlag enables or disables a flag on this environment (and thus any children not overriding it)
+func (e *Env) SetFlag(flag Flag, enabled bool) *Env {
+	if e.flags == nil {
+		e.flags = map[Flag]bool{}
+	}
+	e.flags[flag] = enabled
+	return e
+}
+
+// Enabled returns the value of a flag, as set on this environment, its nearest parent which sets it, or the
+// default value
+func (e *Env) Enabled(flag Flag) bool {
+	for env := e; env != nil; env = env.parent {
+		if enabled, ok := env.flags[flag]; ok {
+			return enabled
+		}
+	}
+	return defaultFlags[flag]
+}
+
+func (e *Env) variableLookup() VariableLookupContext {
+	for env := e; env != nil; env = env.parent {
+		if env.VariablesContext != nil {
+			return env.VariablesContext
+		}
+		if env.Variables != nil {
+			return env.Variables.withContext()
+		}
+	}
+	return nil
+}
+
+func (e *Env) functionCall() FunctionCallContext {
+	for env := e; env != nil; env = env.parent {
+		if env.FunctionsContext != nil {
+			return env.FunctionsContext
+		}
+		if env.NamedFunctions != nil {
+			return env.NamedFunctions.withContext()
+		}
+		if env.Functions != nil {
+			return env.Functions.named().withContext()
+		}
+	}
+	return nil
+}
+
+func (e *Env) operators() *OperatorRegistry {
+	for env := e; env != nil; env = env.parent {
+		if env.Operators != nil {
+			return env.Operators
+		}
+	}
+	return builtinRegistry
+}
+
+func (e *Env) limits() *Limits {
+	for env := e; env != nil; env = env.parent {
+		if env.Limits != nil {
+			return env.Limits
+		}
+	}
+	return nil
+}
+
+// checkFeatures applies the compile time flags of the environment to a token tree, rejecting any disabled
+// syntax
+func (e *Env) checkFeatures(t *Token) error {
+	switch {
+	case t.Name != "" && !e.Enabled(FlagNamedArguments):
+		return fmt.Errorf("named argument %s is not permitted", t.Name)
+	case t.Spread && !e.Enabled(FlagSpreadArguments):
+		return fmt.Errorf("spread arguments are not permitted")
+	case t.Type == TokenTypeDuration && !e.Enabled(FlagDurationLiterals):
+		t.Type = TokenTypeInferredString
+	}
+
+	if t.Type == TokenTypeInferredString && e.Enabled(FlagStrictStrings) {
+		return fmt.Errorf("unquoted string %s is not permitted, strings must be quoted", t.Text)
+	}
+
+	for _, sub := range t.Tokens {
+		err := e.checkFeatures(sub)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CompileWith parses an expression into a program, using the operators and compile time flags of the
+// environment
+func CompileWith(expression string, env *Env) (*Program, error) {
+	if env == nil {
+		env = NewEnv()
+	}
+
+	err := env.limits().checkCompile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := env.operators().parse(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	err = env.checkFeatures(program.root)
+	if err != nil {
+		return nil, err
+	}
+
+	if limits := env.limits(); limits != nil && limits.MaxDepth > 0 {
+		node, err := program.AST()
+		if err != nil {
+			return nil, err
+		}
+		err = limits.checkDepth(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if env.Enabled(FlagConstantFolding) {
+		program.fold(env.limits())
+	}
+	return program, nil
+}
+
+// EvaluateWith compiles and evaluates an expression using the environment
+func EvaluateWith(expression string, env *Env) (any, error) {
+	return EvaluateWithContext(context.Background(), expression, env)
+}
+
+// EvaluateWithContext compiles and evaluates an expression using the environment, passing the context to
+// the callbacks
+func EvaluateWithContext(ctx context.Context, expression string, env *Env) (any, error) {
+	program, err := CompileWith(expression, env)
+	if err != nil {
+		return false, err
+	}
+	return program.EvaluateContext(ctx, env)
+}
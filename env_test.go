@@ -0,0 +1,92 @@
+package eval
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateWithEnv(t *testing.T) {
+	env := &Env{
+		Variables: func(key string) (any, error) {
+			return 4., nil
+		},
+		Functions: fLookup,
+	}
+
+	result, err := EvaluateWith("len('abc') + .x", env)
+	assert.NoError(t, err)
+	assert.Equal(t, 7., result)
+
+	result, err = EvaluateWith("1 + 1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2., result)
+}
+
+func TestChildEnvInheritsAndOverrides(t *testing.T) {
+	registry := NewOperatorRegistry()
+	err := registry.Register(Operator{Symbol: "contains", Precedence: PrecedenceComparison, Func: containsOp})
+	assert.NoError(t, err)
+
+	parent := &Env{
+		Variables: func(key string) (any, error) {
+			return "parent", nil
+		},
+		Functions: func(name string, args ...any) (any, error) {
+			return "parent " + name, nil
+		},
+		Operators: registry,
+	}
+	parent.SetFlag(FlagStrictVariables, true)
+
+	child := parent.NewChild()
+	child.NamedFunctions = func(name string, args []any, namedArgs map[string]any) (any, error) {
+		return fmt.Sprintf("child %s %v", name, namedArgs["n"]), nil
+	}
+	child.SetFlag(FlagStrictStrings, true)
+
+	result, err := EvaluateWith("f(n=1) + ' ' + .x", child)
+	assert.NoError(t, err)
+	assert.Equal(t, "child f 1 parent", result)
+
+	result, err = EvaluateWith(".x contains 'ar'", child)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+
+	result, err = EvaluateWith("f(1)", parent)
+	assert.NoError(t, err)
+	assert.Equal(t, "parent f", result)
+
+	assert.True(t, child.Enabled(FlagStrictVariables))
+	assert.True(t, child.Enabled(FlagStrictStrings))
+	assert.False(t, parent.Enabled(FlagStrictStrings))
+
+	_, err = EvaluateWith(".x == abc", child)
+	assert.Error(t, err)
+
+	_, err = EvaluateWith(".x == abc", parent)
+	assert.NoError(t, err)
+}
+
+func TestEnvFlags(t *testing.T) {
+	env := NewEnv().SetFlag(FlagStrictVariables, true)
+	env.Variables = func(key string) (any, error) {
+		return nil, nil
+	}
+	_, err := EvaluateWith(".missing", env)
+	assert.Error(t, err)
+
+	env = NewEnv().SetFlag(FlagDurationLiterals, false)
+	result, err := EvaluateWith("5m", env)
+	assert.NoError(t, err)
+	assert.Equal(t, "5m", result)
+
+	env = NewEnv().SetFlag(FlagNamedArguments, false)
+	_, err = CompileWith("f(a=1)", env)
+	assert.Error(t, err)
+
+	env = NewEnv().SetFlag(FlagSpreadArguments, false)
+	_, err = CompileWith("f(....a)", env)
+	assert.Error(t, err)
+}
@@ -0,0 +1,21 @@
+package eval
+
+import (
+	"fmt"
+)
+
+// PositionError is an error which occurred at a position within an expression.  Pos is the byte offset
+// of the part of the expression being processed, and Err the underlying error, which can be tested using
+// errors.Is and errors.As.
+type PositionError struct {
+	Pos int
+	Err error
+}
+
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("%v at position %d", e.Err, e.Pos)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
@@ -1,10 +1,12 @@
 package eval
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/frozengoats/kvstore"
 )
@@ -12,6 +14,54 @@ import (
 type VariableLookup func(key string) (any, error)
 type FunctionCall func(name string, args ...any) (any, error)
 
+// NamedFunctionCall is a function callback which additionally receives any named arguments supplied
+// in the call, for example `fn(a, b, timeout=5)` delivers `[a, b]` as args and `{"timeout": 5}` as namedArgs.
+// namedArgs is nil when the call contained no named arguments.
+type NamedFunctionCall func(name string, args []any, namedArgs map[string]any) (any, error)
+
+// named adapts a FunctionCall to the NamedFunctionCall signature, rejecting calls which supply named arguments.
+func (f FunctionCall) named() NamedFunctionCall {
+	if f == nil {
+		return nil
+	}
+
+	return func(name string, args []any, namedArgs map[string]any) (any, error) {
+		if len(namedArgs) > 0 {
+			return nil, fmt.Errorf("function %s does not accept named arguments", name)
+		}
+		return f(name, args...)
+	}
+}
+
+// VariableLookupContext is a variable lookup which receives the context supplied to the evaluation
+type VariableLookupContext func(ctx context.Context, key string) (any, error)
+
+// FunctionCallContext is a function callback which receives the context supplied to the evaluation, along
+// with any named arguments (see NamedFunctionCall)
+type FunctionCallContext func(ctx context.Context, name string, args []any, namedArgs map[string]any) (any, error)
+
+// withContext adapts a VariableLookup to the VariableLookupContext signature, ignoring the context
+func (v VariableLookup) withContext() VariableLookupContext {
+	if v == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, key string) (any, error) {
+		return v(key)
+	}
+}
+
+// withContext adapts a NamedFunctionCall to the FunctionCallContext signature, ignoring the context
+func (f NamedFunctionCall) withContext() FunctionCallContext {
+	if f == nil {
+		return nil
+	}
+
+	return func(ctx context.Context, name string, args []any, namedArgs map[string]any) (any, error) {
+		return f(name, args, namedArgs)
+	}
+}
+
 var subscriptParser = regexp.MustCompile(`^((\[\d+\])|(\.[a-zA-Z_][a-zA-Z_0-9]+))+$`)
 var variableFinder = regexp.MustCompile(`^\.[a-zA-Z_]`)
 
@@ -30,25 +80,10 @@ const (
 	OperatorExponent      string = "**"
 	OperatorDivide        string = "/"
 	Separator             string = ","
+	Assignment            string = "="
+	SpreadPrefix          string = "..."
 )
 
-var operators = map[string]struct{}{
-	OperatorEquals:        {},
-	OperatorUnequals:      {},
-	OperatorGreaterEquals: {},
-	OperatorLessEquals:    {},
-	OperatorGreater:       {},
-	OperatorLess:          {},
-	OperatorAnd:           {},
-	OperatorOr:            {},
-	OperatorPlus:          {},
-	OperatorMinus:         {},
-	OperatorMultiply:      {},
-	OperatorDivide:        {},
-	OperatorExponent:      {},
-	Separator:             {},
-}
-
 const (
 	OpenParenthesis   byte = 40
 	ClosedParenthesis byte = 41
@@ -67,20 +102,6 @@ const (
 	Comma             byte = 44
 )
 
-var operatorChars = map[byte]struct{}{
-	Equals:      {},
-	Exclamation: {},
-	GreaterThan: {},
-	LessThan:    {},
-	Ampersand:   {},
-	Pipe:        {},
-	Plus:        {},
-	Minus:       {},
-	Multiply:    {},
-	Divide:      {},
-	Comma:       {},
-}
-
 type GroupType string
 
 const (
@@ -92,6 +113,11 @@ const (
 type Group struct {
 	Text string
 	Type GroupType
+	// Offset is the byte offset of Text within the expression
+	Offset int
+
+	// span covers a parenthesis group including its parentheses
+	span Span
 }
 
 func CastToFloat64IfApplicable(value any) any {
@@ -123,7 +149,12 @@ func CastToFloat64IfApplicable(value any) any {
 	}
 }
 
+// EmitTokens breaks the group down into tokens, recognizing the builtin operators only
 func (g *Group) EmitTokens() ([]*Token, error) {
+	return g.emitTokens(builtinRegistry)
+}
+
+func (g *Group) emitTokens(r *OperatorRegistry) ([]*Token, error) {
 	var tokens []*Token
 
 	// if this is a group of sub-groups, go recurive
@@ -133,19 +164,31 @@ func (g *Group) EmitTokens() ([]*Token, error) {
 			return nil, err
 		}
 
-		for _, g := range subGroups {
-			subTokens, err := g.EmitTokens()
+		for _, sub := range subGroups {
+			sub.Offset += g.Offset
+			if sub.Type == GroupTypeParenthesis {
+				sub.span.Start += g.Offset
+				sub.span.End += g.Offset
+			}
+			subTokens, err := sub.emitTokens(r)
 			if err != nil {
 				return nil, err
 			}
 			tokens = append(tokens, subTokens...)
 		}
 
+		organized, err := organizeTokens(tokens, r)
+		if err != nil {
+			return nil, err
+		}
+
 		return []*Token{
 			{
 				Text:   g.Text,
 				Type:   TokenTypeGroup,
-				Tokens: organizeTokens(tokens),
+				Tokens: organized,
+				Pos:    g.Offset,
+				span:   g.span,
 			},
 		}, nil
 	}
@@ -155,6 +198,8 @@ func (g *Group) EmitTokens() ([]*Token, error) {
 			{
 				Text: g.Text,
 				Type: TokenTypeString,
+				// the token begins at the opening quotation mark
+				Pos: g.Offset - 1,
 			},
 		}, nil
 	}
@@ -171,25 +216,43 @@ func (g *Group) EmitTokens() ([]*Token, error) {
 		}
 
 		if prevToken != 0 {
-			_, isPrevOperator := operatorChars[prevToken]
-			_, isCurrentOperator := operatorChars[c]
+			isPrevOperator := r.isOperatorChar(prevToken)
+			isCurrentOperator := r.isOperatorChar(c)
 
 			if isPrevOperator != isCurrentOperator || i == len(g.Text) {
-				text := strings.Trim(g.Text[tokenStart:i], " ")
-				toks := strings.Split(text, " ")
+				segmentStart := tokenStart
+				toks := strings.Split(g.Text[tokenStart:i], " ")
 				tokenStart = i
 				for _, tok := range toks {
+					pos := g.Offset + segmentStart
+					segmentStart += len(tok) + 1
 					if len(tok) == 0 {
 						continue
 					}
 
+					if !isPrevOperator && strings.HasPrefix(tok, SpreadPrefix) {
+						// the spread prefix is emitted as its own token, followed by whatever it prefixes
+						tokens = append(tokens, &Token{
+							Text: SpreadPrefix,
+							Type: TokenTypeSpread,
+							Pos:  pos,
+						})
+						tok = tok[len(SpreadPrefix):]
+						pos += len(SpreadPrefix)
+						if len(tok) == 0 {
+							continue
+						}
+					}
+
 					matchesVariable := variableFinder.MatchString(tok)
 
 					var tokenType TokenType
-					_, isOperator := operators[tok]
-					if isOperator {
-						if text == Separator {
+					_, isOperator := r.Lookup(tok)
+					if isOperator || tok == Separator || tok == Assignment {
+						if tok == Separator {
 							tokenType = TokenTypeSeparator
+						} else if tok == Assignment {
+							tokenType = TokenTypeAssignment
 						} else {
 							tokenType = TokenTypeOperator
 						}
@@ -204,6 +267,8 @@ func (g *Group) EmitTokens() ([]*Token, error) {
 						if err != nil {
 							if tok == "true" || tok == "false" {
 								tokenType = TokenTypeBoolean
+							} else if isDurationLiteral(tok) {
+								tokenType = TokenTypeDuration
 							} else {
 								tokenType = TokenTypeInferredString
 							}
@@ -213,8 +278,9 @@ func (g *Group) EmitTokens() ([]*Token, error) {
 					}
 
 					tokens = append(tokens, &Token{
-						Text: text,
+						Text: tok,
 						Type: tokenType,
+						Pos:  pos,
 					})
 				}
 			}
@@ -237,6 +303,9 @@ const (
 	TokenTypeFunction       TokenType = "FUNCTION"
 	TokenTypeSeparator      TokenType = "SEPARATOR"
 	TokenTypeBoolean        TokenType = "BOOLEAN"
+	TokenTypeAssignment     TokenType = "ASSIGNMENT"
+	TokenTypeSpread         TokenType = "SPREAD"
+	TokenTypeDuration       TokenType = "DURATION"
 )
 
 type Token struct {
@@ -244,10 +313,118 @@ type Token struct {
 	Type      TokenType
 	Tokens    []*Token
 	Subscript string
+
+	// Name is set on function argument tokens which were supplied as named arguments (`name=value`)
+	Name string
+	// Spread is set on function argument tokens prefixed with `...`, whose array value is expanded in place
+	Spread bool
+	// Pos is the byte offset within the expression at which the token begins
+	Pos int
+
+	// span covers the source of a parenthesis group, function call or token created from a syntax tree
+	// node, and subscriptEnd is the offset following its subscript.  these preserve the spans of AST nodes.
+	span         Span
+	subscriptEnd int
+}
+
+// end returns the offset following the source of a token
+func (t *Token) end() int {
+	switch {
+	case t.subscriptEnd != 0:
+		return t.subscriptEnd
+	case t.span != Span{}:
+		return t.span.End
+	case t.Type == TokenTypeString:
+		// the text of a string excludes its quotation marks
+		return t.Pos + len(t.Text) + 2
+	default:
+		return t.Pos + len(t.Text)
+	}
+}
+
+// evaluator holds the callbacks used while evaluating a token tree
+type evaluator struct {
+	ctx       context.Context
+	varLookup VariableLookupContext
+	funcCall  FunctionCallContext
+	operators *OperatorRegistry
+
+	strictVariables bool
+
+	// parentCtx is the context supplied by the caller, from which ctx is derived when a timeout is imposed
+	parentCtx context.Context
+	limits    *Limits
+	steps     int
+	calls     int
+}
+
+// callFunction evaluates the argument tokens of a function token and executes the function callback
+func (t *Token) callFunction(e *evaluator) (any, error) {
+	var args []any
+	var namedArgs map[string]any
+	for _, token := range t.Tokens {
+		// these are function arguments in this case, they need to be simplified prior to the call
+		v, err := token.evaluate(e)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case token.Name != "":
+			if namedArgs == nil {
+				namedArgs = map[string]any{}
+			}
+			if _, ok := namedArgs[token.Name]; ok {
+				return nil, fmt.Errorf("named argument %s supplied more than once in call to %s", token.Name, t.Text)
+			}
+			namedArgs[token.Name] = v
+		case len(namedArgs) > 0:
+			return nil, fmt.Errorf("positional argument follows named argument in call to %s", t.Text)
+		case token.Spread:
+			items, ok := toArray(v)
+			if !ok {
+				return nil, fmt.Errorf("cannot spread %T into arguments of %s, only arrays can be spread", v, t.Text)
+			}
+			args = append(args, items...)
+		default:
+			args = append(args, v)
+		}
+	}
+
+	if e.funcCall == nil {
+		return nil, fmt.Errorf("unable to call %s, no function callback was supplied", t.Text)
+	}
+
+	e.calls++
+	if e.limits != nil && e.limits.MaxFunctionCalls > 0 && e.calls > e.limits.MaxFunctionCalls {
+		return nil, &PositionError{Pos: t.Pos, Err: &LimitError{Limit: ErrMaxFunctionCalls, Max: e.limits.MaxFunctionCalls}}
+	}
+
+	// execute the function call with the supplied arguments
+	result, err := e.funcCall(e.ctx, t.Text, args, namedArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.limits.checkSize(result)
+	if err != nil {
+		return nil, &PositionError{Pos: t.Pos, Err: err}
+	}
+	return result, nil
 }
 
 // simplify traverses the token in a depth-first order and evaluates the result
-func (t *Token) evaluate(varLookup VariableLookup, funcCall FunctionCall) (any, error) {
+func (t *Token) evaluate(e *evaluator) (any, error) {
+	// honour cancellation between the evaluation of each node
+	if err := e.ctx.Err(); err != nil {
+		return nil, &PositionError{Pos: t.Pos, Err: e.contextError(err)}
+	}
+
+	e.steps++
+	if e.limits != nil && e.limits.MaxSteps > 0 && e.steps > e.limits.MaxSteps {
+		return nil, &PositionError{Pos: t.Pos, Err: &LimitError{Limit: ErrMaxStepsExceeded, Max: e.limits.MaxSteps}}
+	}
+
 	var curVal any
 	var prevToken = &Token{
 		Type: TokenTypeOperator,
@@ -255,19 +432,7 @@ func (t *Token) evaluate(varLookup VariableLookup, funcCall FunctionCall) (any,
 
 	switch t.Type {
 	case TokenTypeFunction:
-		var args []any
-		for _, token := range t.Tokens {
-			// these are function arguments in this case, they need to be simplified but
-			v, err := token.evaluate(varLookup, funcCall)
-			if err != nil {
-				return nil, err
-			}
-
-			args = append(args, v)
-		}
-
-		// execute the function call with the supplied arguments
-		v, err := funcCall(t.Text, args...)
+		v, err := t.callFunction(e)
 		if err != nil {
 			return nil, err
 		}
@@ -286,12 +451,27 @@ func (t *Token) evaluate(varLookup VariableLookup, funcCall FunctionCall) (any,
 	case TokenTypeNumber:
 		fl, _ := strconv.ParseFloat(t.Text, 64)
 		curVal = fl
+	case TokenTypeDuration:
+		d, _ := time.ParseDuration(t.Text)
+		curVal = d
 	case TokenTypeVariable:
-		varValue, err := varLookup(t.Text)
+		if e.varLookup == nil {
+			return nil, fmt.Errorf("unable to resolve %s, no variable lookup was supplied", t.Text)
+		}
+		varValue, err := e.varLookup(e.ctx, t.Text)
 		if err != nil {
 			return nil, err
 		}
+		if varValue == nil && e.strictVariables {
+			return nil, fmt.Errorf("variable %s is not defined", t.Text)
+		}
 		curVal = varValue
+	case TokenTypeSeparator:
+		return nil, fmt.Errorf("bad expression, argument separator outside of function call")
+	case TokenTypeAssignment:
+		return nil, fmt.Errorf("bad expression, named argument outside of function call")
+	case TokenTypeSpread:
+		return nil, fmt.Errorf("bad expression, spread outside of function call")
 	default:
 		if t.Type != TokenTypeGroup {
 			panic("this should be a group token type and no other")
@@ -308,9 +488,9 @@ func (t *Token) evaluate(varLookup VariableLookup, funcCall FunctionCall) (any,
 				continue
 			}
 
-			v, e := token.evaluate(varLookup, funcCall)
-			if e != nil {
-				return nil, e
+			v, err := token.evaluate(e)
+			if err != nil {
+				return nil, err
 			}
 			value = v
 
@@ -324,41 +504,20 @@ func (t *Token) evaluate(varLookup VariableLookup, funcCall FunctionCall) (any,
 				return nil, fmt.Errorf("bad expression, values must be separated by operators")
 			}
 
-			var err error
-			switch prevToken.Text {
-			case OperatorEquals:
-				curVal, err = EqualsOp(curVal, value)
-			case OperatorUnequals:
-				curVal, err = UnequalsOp(curVal, value)
-			case OperatorGreater:
-				curVal, err = GreaterThanOp(curVal, value)
-			case OperatorGreaterEquals:
-				curVal, err = GreaterThanEqualsOp(curVal, value)
-			case OperatorLess:
-				curVal, err = LessThanOp(curVal, value)
-			case OperatorLessEquals:
-				curVal, err = LessThanEqualsOp(curVal, value)
-			case OperatorAnd:
-				curVal, err = AndOp(curVal, value)
-			case OperatorOr:
-				curVal, err = OrOp(curVal, value)
-			case OperatorPlus:
-				curVal, err = PlusOp(curVal, value)
-			case OperatorMinus:
-				curVal, err = MinusOp(curVal, value)
-			case OperatorMultiply:
-				curVal, err = MultiplyOp(curVal, value)
-			case OperatorExponent:
-				curVal, err = ExponentOp(curVal, value)
-			case OperatorDivide:
-				curVal, err = DivideOp(curVal, value)
-			default:
+			op, ok := e.operators.Lookup(prevToken.Text)
+			if !ok {
 				return nil, fmt.Errorf("unknown operator %s", prevToken.Text)
 			}
+			curVal, err = op.Func(curVal, value)
 			if err != nil {
 				return nil, err
 			}
 
+			err = e.limits.checkSize(curVal)
+			if err != nil {
+				return nil, &PositionError{Pos: prevToken.Pos, Err: err}
+			}
+
 			prevToken = token
 		}
 	}
@@ -388,14 +547,8 @@ func getGroups(expression string) ([]*Group, error) {
 		c := expression[i]
 		if quoteChar == 0 && (c == DoubleQuote || c == SingleQuote) && parenthCount == 0 {
 			quoteChar = c
-			if i-groupStart > 0 {
-				text := strings.Trim(expression[groupStart:i], " ")
-				if len(text) > 0 {
-					groups = append(groups, &Group{
-						Text: text,
-						Type: GroupTypeUnqualified,
-					})
-				}
+			if g := trimmedGroup(expression, groupStart, i, GroupTypeUnqualified); g != nil {
+				groups = append(groups, g)
 			}
 			groupStart = i
 			continue
@@ -403,8 +556,9 @@ func getGroups(expression string) ([]*Group, error) {
 
 		if quoteChar == DoubleQuote && c == DoubleQuote {
 			groups = append(groups, &Group{
-				Text: expression[groupStart+1 : i],
-				Type: GroupTypeString,
+				Text:   expression[groupStart+1 : i],
+				Type:   GroupTypeString,
+				Offset: groupStart + 1,
 			})
 			quoteChar = 0
 			groupStart = i + 1
@@ -413,8 +567,9 @@ func getGroups(expression string) ([]*Group, error) {
 
 		if quoteChar == SingleQuote && c == SingleQuote {
 			groups = append(groups, &Group{
-				Text: expression[groupStart+1 : i],
-				Type: GroupTypeString,
+				Text:   expression[groupStart+1 : i],
+				Type:   GroupTypeString,
+				Offset: groupStart + 1,
 			})
 			quoteChar = 0
 			groupStart = i + 1
@@ -427,14 +582,8 @@ func getGroups(expression string) ([]*Group, error) {
 
 		if parenthCount == 0 && c == OpenParenthesis {
 			parenthCount++
-			if i-groupStart > 0 {
-				text := strings.Trim(expression[groupStart:i], " ")
-				if len(text) > 0 {
-					groups = append(groups, &Group{
-						Text: text,
-						Type: GroupTypeUnqualified,
-					})
-				}
+			if g := trimmedGroup(expression, groupStart, i, GroupTypeUnqualified); g != nil {
+				groups = append(groups, g)
 			}
 			groupStart = i
 			continue
@@ -442,14 +591,17 @@ func getGroups(expression string) ([]*Group, error) {
 
 		if parenthCount == 1 && c == ClosedParenthesis {
 			parenthCount--
-			text := strings.Trim(expression[groupStart+1:i], " ")
-			if len(text) == 0 {
-				return nil, fmt.Errorf("empty parenthesis group contained no contents")
+			// an empty group is permitted here since it may be the argument list of a function call, this is
+			// validated once tokens have been organized
+			g := trimmedGroup(expression, groupStart+1, i, GroupTypeParenthesis)
+			if g == nil {
+				g = &Group{
+					Type:   GroupTypeParenthesis,
+					Offset: groupStart + 1,
+				}
 			}
-			groups = append(groups, &Group{
-				Text: text,
-				Type: GroupTypeParenthesis,
-			})
+			g.span = Span{Start: groupStart, End: i + 1}
+			groups = append(groups, g)
 			groupStart = i + 1
 			continue
 		}
@@ -473,86 +625,127 @@ func getGroups(expression string) ([]*Group, error) {
 		return nil, fmt.Errorf("unclosed quotation mark")
 	}
 
-	if groupStart < len(expression)-1 {
-		text := strings.Trim(expression[groupStart:], " ")
-		if len(text) > 0 {
-			groups = append(groups, &Group{
-				Text: text,
-				Type: GroupTypeUnqualified,
-			})
-		}
+	if g := trimmedGroup(expression, groupStart, len(expression), GroupTypeUnqualified); g != nil {
+		groups = append(groups, g)
 	}
 
 	return groups, nil
 }
 
-func organizeTokens(tokens []*Token) []*Token {
+// trimmedGroup returns a group holding expression[start:end] with surrounding spaces removed, or nil if
+// nothing remains
+func trimmedGroup(expression string, start int, end int, groupType GroupType) *Group {
+	if start >= end {
+		return nil
+	}
+
+	text := strings.TrimLeft(expression[start:end], " ")
+	offset := end - len(text)
+	text = strings.TrimRight(text, " ")
+	if len(text) == 0 {
+		return nil
+	}
+
+	return &Group{
+		Text:   text,
+		Type:   groupType,
+		Offset: offset,
+	}
+}
+
+// newArgument converts the tokens of a single function argument into an argument token, recognizing
+// the named (`name=value`) and spread (`...value`) forms.
+func newArgument(funcName string, tokens []*Token) (*Token, error) {
+	arg := &Token{
+		Type: TokenTypeGroup,
+	}
+	if len(tokens) > 0 {
+		arg.Pos = tokens[0].Pos
+	}
+
+	if len(tokens) > 0 && tokens[0].Type == TokenTypeSpread {
+		arg.Spread = true
+		tokens = tokens[1:]
+		if len(tokens) > 0 && tokens[0].Type == TokenTypeInferredString && (len(tokens) == 1 || tokens[1].Type != TokenTypeGroup) {
+			// a bare word would spread the string itself, which is never what was meant
+			return nil, fmt.Errorf("cannot spread bare word %s in call to %s, spread a variable using %s.%s",
+				tokens[0].Text, funcName, SpreadPrefix, tokens[0].Text)
+		}
+	} else if len(tokens) > 1 && tokens[1].Type == TokenTypeAssignment {
+		if tokens[0].Type != TokenTypeInferredString || tokens[0].Subscript != "" {
+			return nil, fmt.Errorf("invalid argument name %s in call to %s", tokens[0].Text, funcName)
+		}
+		arg.Name = tokens[0].Text
+		tokens = tokens[2:]
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("missing argument value in call to %s", funcName)
+	}
+
+	arg.Tokens = tokens
+	return arg, nil
+}
+
+func organizeTokens(tokens []*Token, r *OperatorRegistry) ([]*Token, error) {
 	var rectifiedTokens []*Token
 	var prevToken *Token
 	for _, t := range tokens {
 		if prevToken != nil && prevToken.Type == TokenTypeInferredString && t.Type == TokenTypeGroup {
 			// a function call will have one or more arguments, thus this token list needs to be converted into a series of groups, one per arg
-			var newTok = &Token{
-				Type: TokenTypeGroup,
+			var argTokens []*Token
+			if len(t.Tokens) == 0 {
+				// a function call without arguments
+				prevToken.Type = TokenTypeFunction
+				prevToken.span = Span{Start: prevToken.Pos, End: t.span.End}
+				continue
 			}
-			for _, subTok := range t.Tokens {
+
+			for _, subTok := range append(t.Tokens, &Token{Type: TokenTypeSeparator}) {
 				if subTok.Type == TokenTypeSeparator {
-					prevToken.Tokens = append(prevToken.Tokens, newTok)
-					newTok = &Token{
-						Type: TokenTypeGroup,
+					arg, err := newArgument(prevToken.Text, argTokens)
+					if err != nil {
+						return nil, err
 					}
+					prevToken.Tokens = append(prevToken.Tokens, arg)
+					argTokens = nil
 					continue
 				}
 
-				newTok.Tokens = append(newTok.Tokens, subTok)
+				argTokens = append(argTokens, subTok)
 			}
-			prevToken.Tokens = append(prevToken.Tokens, newTok)
 			prevToken.Type = TokenTypeFunction
+			prevToken.span = Span{Start: prevToken.Pos, End: t.span.End}
 			// don't reassign prevToken here since this has just swallowed the next token
 			continue
 		}
 
-		// append anything that looks like subscripting to the previous token, so long as it's not an operator or separator
-		if prevToken != nil && prevToken.Type != TokenTypeOperator && prevToken.Type != TokenTypeSeparator && t.Type == TokenTypeInferredString && subscriptParser.MatchString(t.Text) {
+		// append anything that looks like subscripting to the previous token, so long as it's a value rather than an
+		// operator or separator.  subscripts beginning with a key (`.abc`) are emitted as variables, so must
+		// directly follow the value to distinguish `f().abc` from `.x .abc`.
+		if prevToken != nil && isOperand(prevToken) && subscriptParser.MatchString(t.Text) &&
+			(t.Type == TokenTypeInferredString || t.Type == TokenTypeVariable && t.Pos == prevToken.end()) {
 			prevToken.Subscript = t.Text
+			prevToken.subscriptEnd = t.Pos + len(t.Text)
 			continue
 		}
 
-		rectifiedTokens = append(rectifiedTokens, t)
-		prevToken = t
-	}
-
-	var orderedTokens []*Token
-	for _, oper := range []string{
-		OperatorExponent, OperatorDivide, OperatorMultiply, OperatorPlus, OperatorMinus, OperatorEquals, OperatorUnequals, OperatorGreater, OperatorGreaterEquals, OperatorLess, OperatorLessEquals,
-	} {
-		i := 0
-		for i < len(rectifiedTokens) {
-			j := i + 1
-			if j < len(rectifiedTokens)-1 {
-				if rectifiedTokens[j].Type == TokenTypeOperator && rectifiedTokens[j].Text == oper {
-					orderedTokens = append(orderedTokens, &Token{
-						Type:   TokenTypeGroup,
-						Tokens: rectifiedTokens[i : i+3],
-					})
-					i += 3
-					continue
-				}
-			}
-
-			orderedTokens = append(orderedTokens, rectifiedTokens[i])
-			i++
+		if t.Type == TokenTypeGroup && len(t.Tokens) == 0 {
+			return nil, fmt.Errorf("empty parenthesis group contained no contents")
 		}
 
-		// swap them and start on the next loop
-		rectifiedTokens = orderedTokens
-		orderedTokens = nil
+		rectifiedTokens = append(rectifiedTokens, t)
+		prevToken = t
 	}
 
-	return rectifiedTokens
+	return r.groupByPrecedence(rectifiedTokens), nil
 }
 
 func tokenize(expression string) (*Token, error) {
+	return builtinRegistry.tokenize(expression)
+}
+
+func (r *OperatorRegistry) tokenize(expression string) (*Token, error) {
 	groups, err := getGroups(expression)
 	if err != nil {
 		return nil, err
@@ -560,16 +753,21 @@ func tokenize(expression string) (*Token, error) {
 
 	var tokens []*Token
 	for _, g := range groups {
-		toks, err := g.EmitTokens()
+		toks, err := g.emitTokens(r)
 		if err != nil {
 			return nil, err
 		}
 		tokens = append(tokens, toks...)
 	}
 
+	organized, err := organizeTokens(tokens, r)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Token{
 		Type:   TokenTypeGroup,
-		Tokens: organizeTokens(tokens),
+		Tokens: organized,
 	}, nil
 }
 
@@ -585,6 +783,49 @@ func trueIndex(length int, index int) (int, error) {
 	return index, nil
 }
 
+// toArray returns the elements of any array type understood by subscripting as a []any, with numeric
+// elements cast to float64
+func toArray(value any) ([]any, bool) {
+	var items []any
+	switch t := value.(type) {
+	case []any:
+		items = make([]any, 0, len(t))
+		for _, v := range t {
+			items = append(items, CastToFloat64IfApplicable(v))
+		}
+	case []int:
+		for _, v := range t {
+			items = append(items, float64(v))
+		}
+	case []float64:
+		for _, v := range t {
+			items = append(items, v)
+		}
+	case []int64:
+		for _, v := range t {
+			items = append(items, float64(v))
+		}
+	case []string:
+		for _, v := range t {
+			items = append(items, v)
+		}
+	case []byte:
+		for _, v := range t {
+			items = append(items, float64(v))
+		}
+	default:
+		return nil, false
+	}
+
+	return items, true
+}
+
+// Subscript resolves a subscript path such as `.abc[1].def` (the leading `.` is optional) against a value,
+// using the same rules as subscripting within expressions
+func Subscript(value any, path string) (any, error) {
+	return subscriptImmediate(value, path)
+}
+
 func subscriptImmediate(value any, subscript string) (any, error) {
 	nsArray := kvstore.ParseNamespaceString(subscript)
 
@@ -686,15 +927,37 @@ func subscriptImmediate(value any, subscript string) (any, error) {
 // Evaluate evaluates an expression to either true or false, or returns an error if the expression cannot
 // be evaluated.
 func Evaluate(expression string, varLookup VariableLookup, funcCall FunctionCall) (any, error) {
-	tokenGroup, err := tokenize(expression)
+	return EvaluateNamed(expression, varLookup, funcCall.named())
+}
+
+// EvaluateNamed evaluates an expression in the same manner as Evaluate, but delivers function calls to a
+// NamedFunctionCall so that named arguments (`fn(a, timeout=5)`) can be received.
+func EvaluateNamed(expression string, varLookup VariableLookup, funcCall NamedFunctionCall) (any, error) {
+	program, err := Compile(expression)
+	if err != nil {
+		return false, err
+	}
+	return program.EvaluateNamed(varLookup, funcCall)
+}
+
+// EvaluateContext evaluates an expression, passing the context to the callbacks.  evaluation stops once the
+// context is cancelled or its deadline passes, returning a *PositionError wrapping ctx.Err() which records
+// the position reached within the expression.
+func EvaluateContext(ctx context.Context, expression string, varLookup VariableLookupContext, funcCall FunctionCallContext) (any, error) {
+	program, err := Compile(expression)
 	if err != nil {
 		return false, err
 	}
-	return tokenGroup.evaluate(varLookup, funcCall)
+	return program.EvaluateContext(ctx, &Env{
+		VariablesContext: varLookup,
+		FunctionsContext: funcCall,
+	})
 }
 
 func IsTruthy(value any) bool {
 	switch t := value.(type) {
+	case Truthy:
+		return t.Truthy()
 	case string:
 		return len(t) > 0
 	case float64:
@@ -705,6 +968,10 @@ func IsTruthy(value any) bool {
 		return len(t) > 0
 	case bool:
 		return t
+	case time.Duration:
+		return t != 0
+	case time.Time:
+		return !t.IsZero()
 	default:
 		return false
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/frozengoats/kvstore"
 	"github.com/stretchr/testify/assert"
@@ -85,6 +86,28 @@ func TestGetGroupsParenthesis(t *testing.T) {
 	assert.Equal(t, groups[2].Type, GroupTypeString)
 }
 
+func TestGetGroupsTrailingCharacter(t *testing.T) {
+	groups, err := getGroups("1")
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 1) {
+		assert.Equal(t, "1", groups[0].Text)
+	}
+
+	groups, err = getGroups("(2)*3")
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, "*3", groups[1].Text)
+		assert.Equal(t, 3, groups[1].Offset)
+	}
+
+	groups, err = getGroups("'a' b")
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 2) {
+		assert.Equal(t, "b", groups[1].Text)
+		assert.Equal(t, GroupTypeUnqualified, groups[1].Type)
+	}
+}
+
 func TestTokenizerSimple(t *testing.T) {
 	expression := ".Values.abc.def==123"
 	tokenGroup, err := tokenize(expression)
@@ -295,3 +318,147 @@ func TestSubscriptImmediateSubscriptable(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 3, v)
 }
+
+func TestEvaluateNamedArguments(t *testing.T) {
+	var gotArgs []any
+	var gotNamed map[string]any
+	fCall := func(name string, args []any, namedArgs map[string]any) (any, error) {
+		gotArgs = args
+		gotNamed = namedArgs
+		return name, nil
+	}
+
+	result, err := EvaluateNamed("fetch('abc', 2, timeout=5 * 2, mode=fast)", nil, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, "fetch", result)
+	assert.Equal(t, []any{"abc", 2.}, gotArgs)
+	assert.Equal(t, map[string]any{"timeout": 10., "mode": "fast"}, gotNamed)
+
+	_, err = EvaluateNamed("fetch(timeout=5, 'abc')", nil, fCall)
+	assert.Error(t, err)
+
+	_, err = EvaluateNamed("fetch(timeout=5, timeout=6)", nil, fCall)
+	assert.Error(t, err)
+}
+
+func TestEvaluateNamedArgumentsRejectedByFunctionCall(t *testing.T) {
+	_, err := Evaluate("len('abc', extra=1)", nil, fLookup)
+	assert.Error(t, err)
+}
+
+func TestEvaluateSpreadArguments(t *testing.T) {
+	values := kvstore.NewStore()
+	err := values.Set([]any{"a", "b"}, "list")
+	assert.NoError(t, err)
+
+	vLookup := func(key string) (any, error) {
+		k := strings.TrimPrefix(key, ".")
+		return values.Get(kvstore.ParseNamespaceString(k)...), nil
+	}
+
+	var gotArgs []any
+	fCall := func(name string, args ...any) (any, error) {
+		gotArgs = args
+		return len(args), nil
+	}
+
+	result, err := Evaluate("count(1, ....list, ... lines('x\ny'))", vLookup, func(name string, args ...any) (any, error) {
+		if name == "lines" {
+			return fLookup(name, args...)
+		}
+		return fCall(name, args...)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5., result)
+	assert.Equal(t, []any{1., "a", "b", "x", "y"}, gotArgs)
+
+	_, err = Evaluate("count(...'abc')", nil, fCall)
+	assert.Error(t, err)
+
+	// a bare word is rejected when compiled, rather than spreading the string
+	_, err = Compile("count(1, ...list)")
+	assert.EqualError(t, err, "cannot spread bare word list in call to count, spread a variable using ....list")
+
+	result, err = Evaluate("count(... .list)", vLookup, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, 2., result)
+	assert.Equal(t, []any{"a", "b"}, gotArgs)
+}
+
+func TestEvaluateMisplacedArgumentSyntax(t *testing.T) {
+	_, err := Evaluate("a = 1", nil, nil)
+	assert.Error(t, err)
+
+	_, err = Evaluate("... .abc", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestEvaluateDurationLiterals(t *testing.T) {
+	result, err := Evaluate("1h30m + 250ms", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute+250*time.Millisecond, result)
+
+	lastSeen := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	vLookup := func(key string) (any, error) {
+		return lastSeen, nil
+	}
+	fCall := func(name string, args ...any) (any, error) {
+		return lastSeen.Add(10 * time.Minute), nil
+	}
+
+	result, err = Evaluate(".lastSeen < now() - 5m", vLookup, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+
+	result, err = Evaluate("now() - .lastSeen >= 10m && .lastSeen > '2025-01-01T00:00:00Z'", vLookup, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+}
+
+func TestEvaluateEmptyParenthesis(t *testing.T) {
+	_, err := Evaluate("1 + ()", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestSubscript(t *testing.T) {
+	v, err := Subscript(map[string]any{"abc": []any{1., map[string]any{"def": "x"}}}, "abc[1].def")
+	assert.NoError(t, err)
+	assert.Equal(t, "x", v)
+
+	_, err = Subscript(1., "[0]")
+	assert.Error(t, err)
+}
+
+func TestEvaluateSubscriptedFunctionResult(t *testing.T) {
+	fCall := func(name string, args ...any) (any, error) {
+		return map[string]any{"ab": []any{map[string]any{"name": "x"}}}, nil
+	}
+
+	result, err := Evaluate("f().ab[0].name + f().ab[0].name", nil, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, "xx", result)
+
+	result, err = Evaluate("(f()).ab[0].name", nil, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, "x", result)
+
+	// a key only subscripts the value it directly follows
+	for _, expression := range []string{"f() .ab", ".xx .yy", "(.xx) .yy", "'abc' .yy"} {
+		_, err = Parse(expression)
+		assert.ErrorContains(t, err, "values must be separated by operators", expression)
+	}
+}
+
+func TestEvaluateSingleCharacterArgument(t *testing.T) {
+	fCall := func(name string, args ...any) (any, error) {
+		return args, nil
+	}
+
+	result, err := Evaluate("f(1)", nil, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{1.}, result)
+
+	result, err = Evaluate("2 * (3)", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 6., result)
+}
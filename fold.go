@@ -0,0 +1,75 @@
+package eval
+
+import (
+	"time"
+)
+
+// fold folds the constant subexpressions of the program, evaluating builtin operators whose operands are
+// both literals, and simplifying `&&` and `||` with a literal left operand:
+//
+//   - `true && x` and `false || x` become `x`
+//   - `false && x` and `true || x` become `false` and `true`, x is never evaluated
+//
+// operators which fail, or produce a value exceeding the limits, are left to fail when evaluated.
+// function calls are never folded since they need not return the same value each time.
+func (p *Program) fold(limits *Limits) {
+	node, err := p.AST()
+	if err != nil || node == nil {
+		// malformed expressions are reported when evaluated
+		return
+	}
+
+	changed := false
+	folded := Rewrite(node, func(n Node) Node {
+		b, ok := n.(*Binary)
+		if !ok {
+			return n
+		}
+		if _, ok := builtinRegistry.Lookup(b.Op); !ok {
+			return n
+		}
+
+		x, xLiteral := b.X.(*Literal)
+		y, yLiteral := b.Y.(*Literal)
+		switch {
+		case xLiteral && yLiteral:
+			op, _ := p.operators.Lookup(b.Op)
+			v, err := op.Func(x.Value, y.Value)
+			if err != nil || limits.checkSize(v) != nil || !isLiteralValue(v) {
+				return n
+			}
+			changed = true
+			return &Literal{Value: v, Loc: b.Loc}
+		case xLiteral && (b.Op == OperatorAnd || b.Op == OperatorOr):
+			changed = true
+			if IsTruthy(x.Value) == (b.Op == OperatorAnd) {
+				return b.Y
+			}
+			return b.X
+		default:
+			return n
+		}
+	})
+	if !changed {
+		return
+	}
+
+	t, err := p.operators.token(folded)
+	if err != nil {
+		return
+	}
+	p.folded = &Token{
+		Type:   TokenTypeGroup,
+		Tokens: []*Token{t},
+	}
+}
+
+// isLiteralValue reports whether a value can be held by a Literal
+func isLiteralValue(value any) bool {
+	switch value.(type) {
+	case string, float64, bool, time.Duration:
+		return true
+	default:
+		return false
+	}
+}
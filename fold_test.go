@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// foldedString returns the formatted folded form of a program, or an empty string if nothing was folded
+func foldedString(t *testing.T, program *Program) string {
+	if program.folded == nil {
+		return ""
+	}
+	node, err := program.folded.node()
+	assert.NoError(t, err)
+	formatted, err := program.operators.format(node)
+	assert.NoError(t, err)
+	return formatted
+}
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"(60 * 60 * 24) * .days":  "86400 * .days",
+		"true && .x":              ".x",
+		"false || .x":             ".x",
+		"false && f()":            "false",
+		"true || f()":             "true",
+		"'' && f()":               "''",
+		"1 < 2 && .x":             ".x",
+		".x || 'a' + 'b'":         ".x || 'ab'",
+		"'ab' + 'cd' == 'abcd'":   "true",
+		"5m * 2 > .d":             "10m > .d",
+		"f(2 * 3, n=1 - 3)":       "f(6, n=0 - 2)",
+		"f(.x).yz == 2 ** 3 ** 2": "f(.x).yz == 64",
+		"(.x && true) || (1 > 2)": ".x && true || false",
+		"1 / 0 + .x":              "",
+		".x + 1 + 2":              "",
+		"f(1) && g()":             "",
+		"'abc' > 5":               "",
+		"":                        "",
+	}
+
+	for expression, expected := range tests {
+		program, err := Compile(expression)
+		if assert.NoError(t, err, expression) {
+			assert.Equal(t, expected, foldedString(t, program), expression)
+			// the source of the program is retained
+			assert.Equal(t, expression, program.Expression())
+		}
+	}
+}
+
+func TestFoldEvaluate(t *testing.T) {
+	called := false
+	fCall := func(name string, args ...any) (any, error) {
+		called = true
+		return true, nil
+	}
+	vLookup := func(key string) (any, error) {
+		return 2., nil
+	}
+
+	result, err := Evaluate("(60 * 60 * 24) * .days", vLookup, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(172800), result)
+
+	// dead branches are never evaluated
+	result, err = Evaluate("false && check()", vLookup, fCall)
+	assert.NoError(t, err)
+	assert.Equal(t, false, result)
+	assert.False(t, called)
+
+	// failing operators fail when evaluated
+	_, err = Evaluate("1 / 0 + .x", vLookup, fCall)
+	assert.EqualError(t, err, "division by zero error")
+
+	program, err := Compile("false && .x")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".x"}, program.Variables())
+	assert.Equal(t, "false && .x", program.String())
+}
+
+func TestFoldDisabled(t *testing.T) {
+	env := NewEnv().SetFlag(FlagConstantFolding, false)
+	program, err := CompileWith("60 * 60 * .days", env)
+	assert.NoError(t, err)
+	assert.Nil(t, program.folded)
+
+	program, err = CompileWith("60 * 60 * .days", NewEnv())
+	assert.NoError(t, err)
+	assert.Equal(t, "3600 * .days", foldedString(t, program))
+}
+
+func TestFoldLimits(t *testing.T) {
+	env := &Env{Limits: &Limits{MaxStringLength: 3}}
+	program, err := CompileWith("'ab' + 'cd' + .x", env)
+	assert.NoError(t, err)
+	assert.Nil(t, program.folded)
+
+	_, err = program.EvaluateWith(env)
+	assert.ErrorIs(t, err, ErrStringTooLong)
+}
+
+func TestFoldDurationLiteralsDisabled(t *testing.T) {
+	env := NewEnv().SetFlag(FlagDurationLiterals, false)
+	program, err := CompileWith("5m + 'x' + .y", env)
+	assert.NoError(t, err)
+	assert.Equal(t, "'5mx' + .y", foldedString(t, program))
+}
+
+func TestFoldNewProgram(t *testing.T) {
+	program, err := NewProgram(&Binary{Op: "*", X: &Literal{Value: 2.}, Y: &Literal{Value: 3.}})
+	assert.NoError(t, err)
+	assert.Equal(t, "6", foldedString(t, program))
+	assert.Equal(t, "2 * 3", program.String())
+}
@@ -0,0 +1,214 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format returns the canonical form of an expression using the builtin operators, see Program.String
+func Format(expression string) (string, error) {
+	program, err := builtinRegistry.parse(expression)
+	if err != nil {
+		return "", err
+	}
+
+	node, err := program.AST()
+	if err != nil {
+		return "", err
+	}
+	return builtinRegistry.format(node)
+}
+
+// String returns the program as a canonical expression, which parses to the same syntax tree (spans
+// aside).  operators are separated from their operands by single spaces and arguments by `, `, strings
+// are single quoted unless they contain a single quote, and only the parentheses required by precedence
+// and associativity are kept.  unquoted strings are quoted, and subscripted variables are written in full
+// (`.a.b`) rather than as a subscript of a variable.  the source expression is returned unchanged if it
+// cannot be parsed into a syntax tree, or the tree cannot be written as an expression.
+func (p *Program) String() string {
+	node, err := p.AST()
+	if err != nil {
+		return p.expression
+	}
+	formatted, err := p.operators.format(node)
+	if err != nil {
+		return p.expression
+	}
+	return formatted
+}
+
+// format prints a syntax tree as a canonical expression.  expressions have no escape sequences, so an error
+// is returned for a string literal containing both single and double quotes.
+func (r *OperatorRegistry) format(node Node) (string, error) {
+	var err error
+	Inspect(node, func(n Node) bool {
+		if literal, ok := n.(*Literal); ok {
+			if s, ok := literal.Value.(string); ok && !isQuotable(s) {
+				err = &PositionError{Pos: literal.Loc.Start, Err: fmt.Errorf("string %q contains both single and double quotes, which cannot be written as a literal", s)}
+			}
+		}
+		return err == nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	r.print(&b, node)
+	return b.String(), nil
+}
+
+func (r *OperatorRegistry) print(b *strings.Builder, node Node) {
+	switch n := node.(type) {
+	case *Literal:
+		b.WriteString(formatLiteral(n.Value))
+	case *Variable:
+		b.WriteString(n.Path)
+	case *Call:
+		b.WriteString(n.Name)
+		b.WriteByte(OpenParenthesis)
+		for i, arg := range n.Args {
+			if i > 0 {
+				b.WriteString(Separator + " ")
+			}
+			r.print(b, arg)
+		}
+		b.WriteByte(ClosedParenthesis)
+	case *NamedArg:
+		b.WriteString(n.Name)
+		b.WriteString(Assignment)
+		r.print(b, n.Value)
+	case *Unary:
+		b.WriteString(n.Op)
+		r.printOperand(b, n.X, !isPrimary(n.X))
+	case *Binary:
+		op, _ := r.Lookup(n.Op)
+		r.printOperand(b, n.X, r.needsParentheses(op, n.X, false))
+		b.WriteString(" " + n.Op + " ")
+		r.printOperand(b, n.Y, r.needsParentheses(op, n.Y, true))
+	case *SubscriptExpr:
+		// only function calls and quoted strings may be subscripted directly, a subscript following a
+		// variable or another subscript would otherwise be merged into it
+		direct := false
+		switch x := n.X.(type) {
+		case *Call:
+			direct = true
+		case *Literal:
+			_, direct = x.Value.(string)
+		}
+		r.printOperand(b, n.X, !direct)
+		b.WriteString(n.Path)
+	}
+}
+
+func (r *OperatorRegistry) printOperand(b *strings.Builder, node Node, parenthesize bool) {
+	if parenthesize {
+		b.WriteByte(OpenParenthesis)
+	}
+	r.print(b, node)
+	if parenthesize {
+		b.WriteByte(ClosedParenthesis)
+	}
+}
+
+// needsParentheses reports whether an operand of a binary operator must be parenthesized, which is the
+// case when it binds more loosely than the operator, or equally tightly on the side opposing the
+// operator's associativity
+func (r *OperatorRegistry) needsParentheses(op *Operator, operand Node, right bool) bool {
+	if literal, ok := operand.(*Literal); ok {
+		return isNegative(literal.Value)
+	}
+
+	binary, ok := operand.(*Binary)
+	if !ok || op == nil {
+		return false
+	}
+
+	inner, ok := r.Lookup(binary.Op)
+	switch {
+	case !ok:
+		return true
+	case inner.Precedence != op.Precedence:
+		return inner.Precedence < op.Precedence
+	case op.Associativity == AssociativityRight:
+		return !right
+	default:
+		return right
+	}
+}
+
+// isPrimary reports whether a node can be written without parentheses wherever an operand is expected
+func isPrimary(node Node) bool {
+	switch n := node.(type) {
+	case *Binary:
+		return false
+	case *Literal:
+		return !isNegative(n.Value)
+	default:
+		return true
+	}
+}
+
+// formatLiteral writes a literal value in the form it is parsed from.  expressions have no negative
+// literals, so negative numbers and durations are written as a subtraction from zero.
+func formatLiteral(value any) string {
+	switch v := CastToFloat64IfApplicable(value).(type) {
+	case string:
+		if strings.ContainsRune(v, rune(SingleQuote)) {
+			return `"` + v + `"`
+		}
+		return "'" + v + "'"
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "Inf"
+		case math.IsInf(v, -1):
+			return "0 - Inf"
+		case v < 0:
+			return "0 - " + strconv.FormatFloat(-v, 'f', -1, 64)
+		default:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Duration:
+		if v < 0 {
+			return "0s - " + formatDuration(-v)
+		}
+		return formatDuration(v)
+	default:
+		return formatLiteral(fmt.Sprint(value))
+	}
+}
+
+// formatDuration writes a duration without trailing zero units (`1h30m` rather than `1h30m0s`)
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// isQuotable reports whether a string can be written as a literal, which is quoted with whichever quote
+// it does not contain
+func isQuotable(s string) bool {
+	return !strings.ContainsRune(s, rune(SingleQuote)) || !strings.ContainsRune(s, rune(DoubleQuote))
+}
+
+func isNegative(value any) bool {
+	switch v := CastToFloat64IfApplicable(value).(type) {
+	case float64:
+		return v < 0
+	case time.Duration:
+		return v < 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		"":                                    "",
+		".a+.b*2":                             ".a + .b * 2",
+		"(.a + .b) * 2":                       "(.a + .b) * 2",
+		"((.a * .b)) + 2":                     ".a * .b + 2",
+		".a - (.b - .c)":                      ".a - (.b - .c)",
+		"(.a - .b) - .c":                      ".a - .b - .c",
+		"2 ** (3 ** 2)":                       "2 ** (3 ** 2)",
+		"(2 ** 3) ** 2":                       "2 ** 3 ** 2",
+		".a || (.b || .c)":                    ".a || (.b || .c)",
+		"(.a || .b) && .c":                    ".a || .b && .c",
+		".a || (.b && .c)":                    ".a || (.b && .c)",
+		"10 - (2 + 3)":                        "10 - 2 + 3",
+		"(10 - 2) + 3":                        "(10 - 2) + 3",
+		".a == 1 && .b != 2 || .c":            ".a == 1 && .b != 2 || .c",
+		`"abc" == abc`:                        "'abc' == 'abc'",
+		`"it's"`:                              `"it's"`,
+		`"it's" + '"quoted"'`:                 `"it's" + '"quoted"'`,
+		"f( .a,....b , n = 1.50 )":            "f(.a, ....b, n=1.5)",
+		"f(...(.a + .b), n=.x || .y)":         "f(...(.a + .b), n=.x || .y)",
+		"g()":                                 "g()",
+		"f(.x).yz[0]":                         "f(.x).yz[0]",
+		"(f(.x).yz).zz":                       "(f(.x).yz).zz",
+		"'abc'.xy":                            "'abc'.xy",
+		"(.a).bc":                             "(.a).bc",
+		"(1 + 2).xy":                          "(1 + 2).xy",
+		"5m + 90m + 1h0m0s + 1.5s + 0s":       "5m + 1h30m + 1h + 1.5s + 0s",
+		"true  &&   false":                    "true && false",
+		"now() - 5m > .created":               "now() - 5m > .created",
+		"1000000000000000000000 + 0.000001":   "1000000000000000000000 + 0.000001",
+		".a.b[0].c == 'x' || len(.items) > 0": ".a.b[0].c == 'x' || len(.items) > 0",
+	}
+
+	for expression, expected := range tests {
+		formatted, err := Format(expression)
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+		assert.Equal(t, expected, formatted, expression)
+
+		// the canonical form is stable, and parses to the same tree
+		again, err := Format(formatted)
+		assert.NoError(t, err, formatted)
+		assert.Equal(t, formatted, again, formatted)
+		assert.Equal(t, stripSpans(mustParse(t, expression)), stripSpans(mustParse(t, formatted)), expression)
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	for _, expression := range []string{"1 2", "(1 + 2", "1 +"} {
+		_, err := Format(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestProgramString(t *testing.T) {
+	program, err := Compile(".a+1")
+	assert.NoError(t, err)
+	assert.Equal(t, ".a + 1", program.String())
+	assert.Equal(t, ".a+1", program.Expression())
+
+	// an expression which only fails once evaluated is returned as written
+	program, err = Compile("1 2")
+	assert.NoError(t, err)
+	assert.Equal(t, "1 2", program.String())
+
+	registry := NewOperatorRegistry()
+	assert.NoError(t, registry.Register(Operator{Symbol: "in", Precedence: PrecedenceComparison, Func: func(a, b any) (any, error) {
+		return true, nil
+	}}))
+	program, err = registry.Compile("(.a in .b)==true")
+	assert.NoError(t, err)
+	assert.Equal(t, "(.a in .b) == true", program.String())
+}
+
+func TestFormatNewProgram(t *testing.T) {
+	program, err := NewProgram(&Binary{
+		Op: "*",
+		X:  &Literal{Value: -2},
+		Y: &Binary{
+			Op: "+",
+			X:  &SubscriptExpr{X: &Variable{Path: ".a"}, Path: ".bc"},
+			Y:  &Literal{Value: -5 * time.Minute},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "(0 - 2) * ((.a).bc + (0s - 5m))", program.String())
+	assert.Equal(t, program.String(), program.Expression())
+
+	result, err := program.Evaluate(func(key string) (any, error) {
+		return map[string]any{"bc": 10 * time.Minute}, nil
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, -10*time.Minute, result)
+
+	// expressions have no escape sequences, so a string holding both quotes cannot be written
+	_, err = NewProgram(&Binary{Op: "+", X: &Variable{Path: ".a"}, Y: &Literal{Value: `it's "quoted"`, Loc: Span{Start: 5, End: 19}}})
+	var posErr *PositionError
+	assert.True(t, errors.As(err, &posErr))
+	assert.Equal(t, 5, posErr.Pos)
+}
+
+func mustParse(t *testing.T, expression string) Node {
+	node, err := Parse(expression)
+	assert.NoError(t, err, expression)
+	return node
+}
+
+// stripSpans returns a copy of the tree with every span zeroed
+func stripSpans(node Node) Node {
+	if node == nil {
+		return nil
+	}
+	return Rewrite(node, func(n Node) Node {
+		setSpan(n, Span{})
+		if b, ok := n.(*Binary); ok {
+			b.OpPos = 0
+		}
+		return n
+	})
+}
@@ -0,0 +1,90 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ReferenceVersion is the version of the JSON function reference format
+const ReferenceVersion = 1
+
+// Reference is the JSON function reference produced by ReferenceJSON
+type Reference struct {
+	Version   int        `json:"version"`
+	Functions []Function `json:"functions"`
+}
+
+// Signature returns the signature of the function as written in expressions, annotated with types
+// (`repeat(s string, n integer) string`)
+func (f Function) Signature() string {
+	var params []string
+	for i, p := range f.Params {
+		if f.Variadic && i == len(f.Params)-1 {
+			params = append(params, fmt.Sprintf("%s ...%s", p.Name, p.Type))
+			continue
+		}
+		params = append(params, fmt.Sprintf("%s %s", p.Name, p.Type))
+	}
+	return fmt.Sprintf("%s(%s) %s", f.Name, strings.Join(params, ", "), f.Returns)
+}
+
+// ReferenceJSON returns a JSON reference of all registered functions, ordered by name
+func (r *FunctionRegistry) ReferenceJSON() ([]byte, error) {
+	return ReferenceJSON(r.Functions())
+}
+
+// ReferenceMarkdown returns a Markdown reference of all registered functions, ordered by name
+func (r *FunctionRegistry) ReferenceMarkdown() string {
+	return ReferenceMarkdown(r.Functions())
+}
+
+// ReferenceJSON returns a JSON reference of the functions in the order supplied, allowing the functions of
+// a registry to be documented along with those of the standard libraries
+func ReferenceJSON(functions []Function) ([]byte, error) {
+	if functions == nil {
+		functions = []Function{}
+	}
+	return json.MarshalIndent(Reference{
+		Version:   ReferenceVersion,
+		Functions: functions,
+	}, "", "  ")
+}
+
+// ReferenceMarkdown returns a Markdown reference of the functions in the order supplied, see ReferenceJSON
+func ReferenceMarkdown(functions []Function) string {
+	var b strings.Builder
+	b.WriteString("# functions\n")
+	for _, f := range functions {
+		fmt.Fprintf(&b, "\n## %s\n`%s`\n", f.Name, f.Signature())
+		if f.Description != "" {
+			fmt.Fprintf(&b, "\n%s\n", f.Description)
+		}
+
+		if len(f.Params) > 0 {
+			b.WriteString("\n| parameter | type | description |\n| -------- | ------- | ------- |\n")
+			for i, p := range f.Params {
+				typ := p.Type
+				if f.Variadic && i == len(f.Params)-1 {
+					typ = "..." + typ
+				}
+				fmt.Fprintf(&b, "| `%s` | %s | %s |\n", p.Name, typ, escapeTableCell(p.Description))
+			}
+		}
+
+		fmt.Fprintf(&b, "\nreturns %s\n", f.Returns)
+
+		if len(f.Examples) > 0 {
+			b.WriteString("\nexamples:\n```\n")
+			for _, example := range f.Examples {
+				fmt.Fprintf(&b, "%s\n", example)
+			}
+			b.WriteString("```\n")
+		}
+	}
+	return b.String()
+}
+
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
@@ -0,0 +1,92 @@
+package eval
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDocumentedRegistry(t *testing.T) *FunctionRegistry {
+	r := NewFunctionRegistry()
+	assert.NoError(t, r.RegisterFunction(Function{
+		Name:        "repeat",
+		Func:        func(s string, n int) string { return strings.Repeat(s, n) },
+		Description: "repeats a string | n times",
+		Params: []Param{
+			{Name: "s", Description: "the string to repeat"},
+			{Name: "n", Description: "the number of repetitions"},
+		},
+		Examples: []string{"repeat('ab', 3) == 'ababab'"},
+	}))
+	assert.NoError(t, r.Register("sum", func(values ...float64) float64 { return 0 }))
+	return r
+}
+
+func TestFunctionMetadata(t *testing.T) {
+	r := newDocumentedRegistry(t)
+
+	f, ok := r.Lookup("repeat")
+	assert.True(t, ok)
+	assert.Equal(t, "repeat(s string, n integer) string", f.Signature())
+
+	f, ok = r.Lookup("sum")
+	assert.True(t, ok)
+	assert.True(t, f.Variadic)
+	assert.Equal(t, "sum(arg1 ...number) number", f.Signature())
+
+	_, ok = r.Lookup("nope")
+	assert.False(t, ok)
+
+	// descriptions are copies, modifying them leaves the registry unchanged
+	f, _ = r.Lookup("repeat")
+	f.Params[0].Name = "changed"
+	f.Examples[0] = "changed"
+	functions := r.Functions()
+	functions[0].Params[1].Type = "changed"
+	f, _ = r.Lookup("repeat")
+	assert.Equal(t, "repeat(s string, n integer) string", f.Signature())
+	assert.Equal(t, []string{"repeat('ab', 3) == 'ababab'"}, f.Examples)
+
+	assert.Error(t, r.RegisterFunction(Function{
+		Name:   "bad",
+		Func:   func(a string, b string) string { return a + b },
+		Params: []Param{{Name: "a"}},
+	}))
+}
+
+func TestFunctionReferenceJSON(t *testing.T) {
+	b, err := newDocumentedRegistry(t).ReferenceJSON()
+	assert.NoError(t, err)
+
+	var reference Reference
+	assert.NoError(t, json.Unmarshal(b, &reference))
+	assert.Equal(t, ReferenceVersion, reference.Version)
+	assert.Len(t, reference.Functions, 2)
+	assert.Equal(t, "repeat", reference.Functions[0].Name)
+	assert.Equal(t, Param{Name: "n", Type: "integer", Description: "the number of repetitions"}, reference.Functions[0].Params[1])
+	assert.Equal(t, []string{"repeat('ab', 3) == 'ababab'"}, reference.Functions[0].Examples)
+	assert.Equal(t, "number", reference.Functions[1].Returns)
+
+	b, err = NewFunctionRegistry().ReferenceJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `"functions": []`)
+}
+
+func TestFunctionReferenceMarkdown(t *testing.T) {
+	expected := "# functions\n" +
+		"\n## repeat\n`repeat(s string, n integer) string`\n" +
+		"\nrepeats a string | n times\n" +
+		"\n| parameter | type | description |\n| -------- | ------- | ------- |\n" +
+		"| `s` | string | the string to repeat |\n" +
+		"| `n` | integer | the number of repetitions |\n" +
+		"\nreturns string\n" +
+		"\nexamples:\n```\nrepeat('ab', 3) == 'ababab'\n```\n" +
+		"\n## sum\n`sum(arg1 ...number) number`\n" +
+		"\n| parameter | type | description |\n| -------- | ------- | ------- |\n" +
+		"| `arg1` | ...number |  |\n" +
+		"\nreturns number\n"
+
+	assert.Equal(t, expected, newDocumentedRegistry(t).ReferenceMarkdown())
+}
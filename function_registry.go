@@ -0,0 +1,346 @@
+package eval
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// FunctionRegistry holds plain Go functions, adapting them to the evaluator by converting and validating
+// the arguments of each call.  the registry's Call method is a FunctionCall, and returns ErrUnknownFunction
+// for names which are not registered so that it can be composed with other callbacks using ChainFunctions.
+type FunctionRegistry struct {
+	functions map[string]*registeredFunction
+}
+
+type registeredFunction struct {
+	Function
+	value reflect.Value
+	ftype reflect.Type
+}
+
+// Param describes a function parameter
+type Param struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// Function describes a function held by a FunctionRegistry, along with the documentation presented to
+// expression authors.  when registering, parameter and return types are derived from Func and need not be
+// supplied.
+type Function struct {
+	Name        string   `json:"name"`
+	Func        any      `json:"-"`
+	Description string   `json:"description,omitempty"`
+	Params      []Param  `json:"params"`
+	Variadic    bool     `json:"variadic,omitempty"`
+	Returns     string   `json:"returns"`
+	Examples    []string `json:"examples,omitempty"`
+}
+
+// NewFunctionRegistry returns an empty function registry
+func NewFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{
+		functions: map[string]*registeredFunction{},
+	}
+}
+
+// Register adds a Go function to the registry under the name.  the function may take any number of
+// parameters (including a trailing variadic parameter), and must return either a single value, or a value
+// and an error.  arguments are converted to the parameter types as follows, any other mismatch failing the
+// call with a descriptive error:
+//
+//   - numbers are converted to any numeric type, integer types requiring a whole number within range
+//   - arrays are converted to slices, and mappings to maps keyed by string, converting each item
+//   - any other value must be assignable to the parameter type (`string`, `bool`, `time.Time`, `any`, etc.)
+//   - nil is accepted for pointer, interface, slice and map parameters
+//
+// numeric results are returned as float64.
+func (r *FunctionRegistry) Register(name string, fn any) error {
+	return r.RegisterFunction(Function{
+		Name: name,
+		Func: fn,
+	})
+}
+
+// RegisterFunction adds a documented function to the registry, see Register for the permitted function
+// signatures.  parameters may be omitted, in which case they are named by position (`arg1`), otherwise one
+// must be supplied for each parameter of Func.
+func (r *FunctionRegistry) RegisterFunction(f Function) error {
+	name := f.Name
+	if name == "" {
+		return fmt.Errorf("function name must not be empty")
+	}
+
+	if _, ok := r.functions[name]; ok {
+		return fmt.Errorf("function %s is already registered", name)
+	}
+
+	value := reflect.ValueOf(f.Func)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return fmt.Errorf("function %s must be a func, got %T", name, f.Func)
+	}
+
+	ftype := value.Type()
+	switch {
+	case ftype.NumOut() == 1 && ftype.Out(0) != errorType:
+	case ftype.NumOut() == 2 && ftype.Out(1) == errorType:
+	default:
+		return fmt.Errorf("function %s must return a value, or a value and an error", name)
+	}
+
+	if len(f.Params) != 0 && len(f.Params) != ftype.NumIn() {
+		return fmt.Errorf("function %s describes %d parameter(s), but takes %d", name, len(f.Params), ftype.NumIn())
+	}
+
+	params := make([]Param, ftype.NumIn())
+	copy(params, f.Params)
+	for i := range params {
+		if params[i].Name == "" {
+			params[i].Name = fmt.Sprintf("arg%d", i+1)
+		}
+		if params[i].Type == "" {
+			t := ftype.In(i)
+			if ftype.IsVariadic() && i == ftype.NumIn()-1 {
+				t = t.Elem()
+			}
+			params[i].Type = docTypeName(t)
+		}
+	}
+	f.Params = params
+	f.Variadic = ftype.IsVariadic()
+	if f.Returns == "" {
+		f.Returns = docTypeName(ftype.Out(0))
+	}
+	f.Examples = slices.Clone(f.Examples)
+
+	r.functions[name] = &registeredFunction{
+		Function: f,
+		value:    value,
+		ftype:    ftype,
+	}
+	return nil
+}
+
+// Lookup returns the description of the named function
+func (r *FunctionRegistry) Lookup(name string) (Function, bool) {
+	f, ok := r.functions[name]
+	if !ok {
+		return Function{}, false
+	}
+	return f.clone(), true
+}
+
+// Functions returns the descriptions of all registered functions, ordered by name
+func (r *FunctionRegistry) Functions() []Function {
+	var functions []Function
+	for _, name := range r.Names() {
+		functions = append(functions, r.functions[name].clone())
+	}
+	return functions
+}
+
+// clone returns a copy of the description which shares no storage with the registry
+func (f *registeredFunction) clone() Function {
+	c := f.Function
+	c.Params = slices.Clone(c.Params)
+	c.Examples = slices.Clone(c.Examples)
+	return c
+}
+
+// Names returns the names of all registered functions, in sorted order
+func (r *FunctionRegistry) Names() []string {
+	return slices.Sorted(maps.Keys(r.functions))
+}
+
+// Call executes the named function, it is compatible with FunctionCall
+func (r *FunctionRegistry) Call(name string, args ...any) (any, error) {
+	f, ok := r.functions[name]
+	if !ok {
+		return nil, UnknownFunction(name)
+	}
+	return f.call(args)
+}
+
+func (f *registeredFunction) call(args []any) (any, error) {
+	numIn := f.ftype.NumIn()
+	variadic := f.ftype.IsVariadic()
+
+	switch {
+	case variadic && len(args) < numIn-1:
+		return nil, fmt.Errorf("function %s expects at least %d argument(s), got %d", f.Name, numIn-1, len(args))
+	case !variadic && len(args) != numIn:
+		return nil, fmt.Errorf("function %s expects %d argument(s), got %d", f.Name, numIn, len(args))
+	}
+
+	in := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		var paramType reflect.Type
+		if variadic && i >= numIn-1 {
+			paramType = f.ftype.In(numIn - 1).Elem()
+		} else {
+			paramType = f.ftype.In(i)
+		}
+
+		v, err := convertArgument(arg, paramType)
+		if err != nil {
+			return nil, fmt.Errorf("function %s argument %d %w", f.Name, i+1, err)
+		}
+		in = append(in, v)
+	}
+
+	out := f.value.Call(in)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	return CastToFloat64IfApplicable(out[0].Interface()), nil
+}
+
+// convertArgument converts an evaluated value to the parameter type, the returned error describes the
+// mismatch and is prefixed by the caller
+func convertArgument(value any, t reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			return reflect.Zero(t), nil
+		default:
+			return reflect.Value{}, fmt.Errorf("must be a %s, got nil", typeName(t))
+		}
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool:
+		// named string and boolean types
+		if v.Kind() == t.Kind() {
+			return v.Convert(t), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		n, ok := CastToFloat64IfApplicable(value).(float64)
+		if !ok {
+			break
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := CastToFloat64IfApplicable(value).(float64)
+		if !ok {
+			break
+		}
+		result := reflect.New(t).Elem()
+		if n != float64(int64(n)) || result.OverflowInt(int64(n)) {
+			return reflect.Value{}, fmt.Errorf("must be a whole number representable as %s, got %v", t, n)
+		}
+		result.SetInt(int64(n))
+		return result, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := CastToFloat64IfApplicable(value).(float64)
+		if !ok {
+			break
+		}
+		result := reflect.New(t).Elem()
+		if n < 0 || n != float64(uint64(n)) || result.OverflowUint(uint64(n)) {
+			return reflect.Value{}, fmt.Errorf("must be a whole number representable as %s, got %v", t, n)
+		}
+		result.SetUint(uint64(n))
+		return result, nil
+	case reflect.Slice:
+		items, ok := toArray(value)
+		if !ok {
+			break
+		}
+		result := reflect.MakeSlice(t, 0, len(items))
+		for i, item := range items {
+			v, err := convertArgument(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("item %d %w", i, err)
+			}
+			result = reflect.Append(result, v)
+		}
+		return result, nil
+	case reflect.Map:
+		m, ok := value.(map[string]any)
+		if !ok || t.Key().Kind() != reflect.String {
+			break
+		}
+		result := reflect.MakeMapWithSize(t, len(m))
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			v, err := convertArgument(m[k], t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %s %w", k, err)
+			}
+			result.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), v)
+		}
+		return result, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("must be a %s, got %T", typeName(t), value)
+}
+
+// typeName describes a parameter type in the terms used by expressions
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "whole number"
+	case reflect.Slice:
+		return "array"
+	case reflect.Map:
+		return "mapping"
+	default:
+		return t.String()
+	}
+}
+
+// docTypeName describes a parameter or return type for documentation
+func docTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any"
+		}
+		return t.String()
+	case reflect.Slice:
+		return "array of " + docTypeName(t.Elem())
+	case reflect.Map:
+		return "mapping of " + docTypeName(t.Elem())
+	default:
+		return typeName(t)
+	}
+}
+
+// Signatures returns the type signatures of all registered functions, for use in a Schema
+func (r *FunctionRegistry) Signatures() map[string]Signature {
+	signatures := make(map[string]Signature, len(r.functions))
+	for name, f := range r.functions {
+		sig := Signature{
+			Variadic: f.Variadic,
+			Returns:  TypeOf(f.ftype.Out(0)),
+		}
+		for i := range f.ftype.NumIn() {
+			t := f.ftype.In(i)
+			if f.Variadic && i == f.ftype.NumIn()-1 {
+				t = t.Elem()
+			}
+			sig.Params = append(sig.Params, TypeOf(t))
+		}
+		signatures[name] = sig
+	}
+	return signatures
+}
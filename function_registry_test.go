@@ -0,0 +1,112 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type level string
+
+func newTestFunctionRegistry(t *testing.T) *FunctionRegistry {
+	r := NewFunctionRegistry()
+	for name, fn := range map[string]any{
+		"repeat": func(s string, n int) string { return strings.Repeat(s, n) },
+		"half":   func(n float64) (float64, error) { return n / 2, nil },
+		"count":  func(items []string) int { return len(items) },
+		"sum": func(first float64, rest ...float64) float64 {
+			for _, n := range rest {
+				first += n
+			}
+			return first
+		},
+		"describe": func(m map[string]int) string { return fmt.Sprint(m) },
+		"kind":     func(v any) string { return fmt.Sprintf("%T", v) },
+		"shout":    func(l level) string { return strings.ToUpper(string(l)) },
+		"byte":     func(b uint8) uint8 { return b },
+		"later":    func(t time.Time, d time.Duration) time.Time { return t.Add(d) },
+		"fail":     func() (any, error) { return nil, errors.New("failed") },
+	} {
+		assert.NoError(t, r.Register(name, fn), name)
+	}
+	return r
+}
+
+func TestFunctionRegistry(t *testing.T) {
+	r := newTestFunctionRegistry(t)
+	vLookup := func(key string) (any, error) {
+		switch key {
+		case ".names":
+			return []any{"a", "b", "c"}, nil
+		case ".nums":
+			return []float64{1, 2}, nil
+		case ".weights":
+			return map[string]any{"a": 1., "b": 2}, nil
+		default:
+			return time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+
+	for expression, expected := range map[string]any{
+		"repeat('ab', 3)":            "ababab",
+		"half(5)":                    2.5,
+		"count(.names)":              3.,
+		"sum(1)":                     1.,
+		"sum(1, 2, 3)":               6.,
+		"sum(1, ....nums)":           4.,
+		"describe(.weights)":         "map[a:1 b:2]",
+		"kind('x') + kind(1)":        "stringfloat64",
+		"kind(.names)":               "[]interface {}",
+		"shout('warn')":              "WARN",
+		"byte(255)":                  255.,
+		"later(.start, 1h) > .start": true,
+		"repeat('x', half(4)) + 'y'": "xxy",
+	} {
+		result, err := Evaluate(expression, vLookup, r.Call)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestFunctionRegistryErrors(t *testing.T) {
+	r := newTestFunctionRegistry(t)
+
+	for expression, message := range map[string]string{
+		"repeat('ab')":      "function repeat expects 2 argument(s), got 1",
+		"repeat(1, 2)":      "function repeat argument 1 must be a string, got float64",
+		"repeat('a', 1.5)":  "function repeat argument 2 must be a whole number representable as int, got 1.5",
+		"sum()":             "function sum expects at least 1 argument(s), got 0",
+		"sum(1, 'x')":       "function sum argument 2 must be a number, got string",
+		"byte(256)":         "function byte argument 1 must be a whole number representable as uint8, got 256",
+		"later('soon', 1h)": "function later argument 1 must be a time.Time, got string",
+		"fail()":            "failed",
+	} {
+		_, err := Evaluate(expression, nil, r.Call)
+		assert.EqualError(t, err, message, expression)
+	}
+
+	_, err := r.Call("nope")
+	assert.ErrorIs(t, err, ErrUnknownFunction)
+}
+
+func TestFunctionRegistryRegister(t *testing.T) {
+	r := NewFunctionRegistry()
+	assert.NoError(t, r.Register("f", func() bool { return true }))
+	assert.Error(t, r.Register("f", func() bool { return true }))
+	assert.Error(t, r.Register("", func() bool { return true }))
+	assert.Error(t, r.Register("g", 5))
+	assert.Error(t, r.Register("g", func() {}))
+	assert.Error(t, r.Register("g", func() error { return nil }))
+	assert.Error(t, r.Register("g", func() (string, string) { return "", "" }))
+	assert.Equal(t, []string{"f"}, r.Names())
+
+	result, err := Evaluate("f() && g(1)", nil, ChainFunctions(r.Call, func(name string, args ...any) (any, error) {
+		return true, nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+}
@@ -0,0 +1,32 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownFunction is returned (wrapped) by function callbacks which do not implement the requested
+// function, allowing several callbacks to be chained together using ChainFunctions
+var ErrUnknownFunction = errors.New("unknown function")
+
+// UnknownFunction returns an error wrapping ErrUnknownFunction for the named function
+func UnknownFunction(name string) error {
+	return fmt.Errorf("%w %s", ErrUnknownFunction, name)
+}
+
+// ChainFunctions returns a FunctionCall which offers each call to the supplied callbacks in order, until one
+// returns anything other than ErrUnknownFunction.  this allows function libraries to be composed with host
+// functions, where earlier callbacks take priority.
+func ChainFunctions(calls ...FunctionCall) FunctionCall {
+	return func(name string, args ...any) (any, error) {
+		for _, call := range calls {
+			result, err := call(name, args...)
+			if errors.Is(err, ErrUnknownFunction) {
+				continue
+			}
+			return result, err
+		}
+
+		return nil, UnknownFunction(name)
+	}
+}
@@ -0,0 +1,37 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainFunctions(t *testing.T) {
+	first := func(name string, args ...any) (any, error) {
+		if name == "one" {
+			return "first", nil
+		}
+		return nil, UnknownFunction(name)
+	}
+	second := func(name string, args ...any) (any, error) {
+		switch name {
+		case "one", "two":
+			return "second", nil
+		case "bad":
+			return nil, errors.New("failed")
+		default:
+			return nil, UnknownFunction(name)
+		}
+	}
+
+	result, err := Evaluate("one() + ' ' + two()", nil, ChainFunctions(first, second))
+	assert.NoError(t, err)
+	assert.Equal(t, "first second", result)
+
+	_, err = Evaluate("bad()", nil, ChainFunctions(first, second))
+	assert.EqualError(t, err, "failed")
+
+	_, err = Evaluate("three()", nil, ChainFunctions(first, second))
+	assert.ErrorIs(t, err, ErrUnknownFunction)
+}
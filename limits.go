@@ -0,0 +1,197 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Limits bounds the resources used to compile and evaluate an expression, protecting hosts which evaluate
+// untrusted expressions.  a zero value for any limit leaves it unbounded.  limits are only enforced through
+// an Env: MaxExpressionLength and MaxDepth when compiling via CompileWith, the remainder when evaluating
+// with one of the Env taking methods (Program.EvaluateWith, EvaluateWith, etc).  Compile, Evaluate and the
+// other functions taking no Env are unbounded.
+//
+// MaxStringLength and MaxArrayLength are checked against each value once it has been produced, so they
+// bound the values kept and passed on by an evaluation rather than the memory allocated producing them.
+// functions can check the size of a result before allocating it using the limits of the evaluation,
+// returned by LimitsFromContext.
+type Limits struct {
+	// MaxExpressionLength is the maximum length of an expression in bytes
+	MaxExpressionLength int
+	// MaxDepth is the maximum nesting depth of the expression.  parentheses are counted before the
+	// expression is parsed, then its syntax tree, in which each operator, function call and subscript nests
+	// its operands a level deeper (`f(1 + 2 * 3)` has a depth of 3)
+	MaxDepth int
+	// MaxSteps is the maximum number of expression nodes (values, groups and calls) evaluated
+	MaxSteps int
+	// MaxFunctionCalls is the maximum number of function calls made
+	MaxFunctionCalls int
+	// MaxStringLength is the maximum length in bytes of a string produced by an operator or function
+	MaxStringLength int
+	// MaxArrayLength is the maximum number of items in an array produced by an operator or function
+	MaxArrayLength int
+	// Timeout is the maximum wall-clock duration of an evaluation
+	Timeout time.Duration
+}
+
+// the sentinel errors identifying each limit, use errors.Is to test which limit was exceeded
+var (
+	ErrExpressionTooLong = errors.New("expression exceeds maximum length")
+	ErrMaxDepthExceeded  = errors.New("expression exceeds maximum nesting depth")
+	ErrMaxStepsExceeded  = errors.New("evaluation exceeds maximum steps")
+	ErrMaxFunctionCalls  = errors.New("evaluation exceeds maximum function calls")
+	ErrStringTooLong     = errors.New("string exceeds maximum length")
+	ErrArrayTooLong      = errors.New("array exceeds maximum length")
+	ErrEvaluationTimeout = errors.New("evaluation exceeds timeout")
+)
+
+// LimitError is returned when a limit is exceeded.  Limit is the sentinel error identifying the limit and
+// Max its configured value, Err holds the underlying cause where there is one (context.DeadlineExceeded
+// for a timeout).  errors occurring during evaluation are additionally wrapped in a PositionError.
+type LimitError struct {
+	Limit error
+	Max   any
+	Err   error
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v (limit %v)", e.Limit, e.Max)
+}
+
+func (e *LimitError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Limit, e.Err}
+	}
+	return []error{e.Limit}
+}
+
+// checkCompile enforces the compile time limits against an expression, prior to it being parsed
+func (l *Limits) checkCompile(expression string) error {
+	if l == nil {
+		return nil
+	}
+
+	if l.MaxExpressionLength > 0 && len(expression) > l.MaxExpressionLength {
+		return &LimitError{Limit: ErrExpressionTooLong, Max: l.MaxExpressionLength}
+	}
+
+	if l.MaxDepth > 0 {
+		// the depth is measured ahead of parsing, since the parser itself recurses for each level of nesting
+		depth := 0
+		var quoteChar byte
+		for i := range len(expression) {
+			c := expression[i]
+			switch {
+			case quoteChar != 0:
+				if c == quoteChar {
+					quoteChar = 0
+				}
+			case c == SingleQuote || c == DoubleQuote:
+				quoteChar = c
+			case c == OpenParenthesis:
+				depth++
+				if depth > l.MaxDepth {
+					return &PositionError{Pos: i, Err: &LimitError{Limit: ErrMaxDepthExceeded, Max: l.MaxDepth}}
+				}
+			case c == ClosedParenthesis:
+				depth--
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkDepth enforces the depth limit against the syntax tree of a parsed expression
+func (l *Limits) checkDepth(node Node) error {
+	if l == nil || l.MaxDepth <= 0 || node == nil {
+		return nil
+	}
+	return l.checkNodeDepth(node, 0)
+}
+
+func (l *Limits) checkNodeDepth(node Node, depth int) error {
+	switch node.(type) {
+	case *Binary, *Unary, *Call, *SubscriptExpr:
+		depth++
+		if depth > l.MaxDepth {
+			return &PositionError{Pos: node.Span().Start, Err: &LimitError{Limit: ErrMaxDepthExceeded, Max: l.MaxDepth}}
+		}
+	}
+
+	for _, child := range children(node) {
+		err := l.checkNodeDepth(child, depth)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckStringLength returns a *LimitError if a string of n bytes would exceed MaxStringLength, allowing
+// functions to check the length of a string before producing it.  nil limits are unbounded.
+func (l *Limits) CheckStringLength(n int) error {
+	if l != nil && l.MaxStringLength > 0 && n > l.MaxStringLength {
+		return &LimitError{Limit: ErrStringTooLong, Max: l.MaxStringLength}
+	}
+	return nil
+}
+
+// CheckArrayLength returns a *LimitError if an array of n items would exceed MaxArrayLength, allowing
+// functions to check the length of an array before producing it.  nil limits are unbounded.
+func (l *Limits) CheckArrayLength(n int) error {
+	if l != nil && l.MaxArrayLength > 0 && n > l.MaxArrayLength {
+		return &LimitError{Limit: ErrArrayTooLong, Max: l.MaxArrayLength}
+	}
+	return nil
+}
+
+// checkSize enforces the string and array length limits against a value produced during evaluation
+func (l *Limits) checkSize(value any) error {
+	if l == nil {
+		return nil
+	}
+
+	switch t := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return l.CheckStringLength(len(t))
+	case []any:
+		return l.CheckArrayLength(len(t))
+	default:
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Slice {
+			return l.CheckArrayLength(v.Len())
+		}
+		return nil
+	}
+}
+
+type limitsKey struct{}
+
+// withLimits returns a context carrying the limits of an evaluation
+func withLimits(ctx context.Context, limits *Limits) context.Context {
+	if limits == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, limitsKey{}, limits)
+}
+
+// LimitsFromContext returns the limits of the evaluation whose context was passed to a callback (see
+// FunctionCallContext and VariableLookupContext), or nil if the evaluation is unbounded
+func LimitsFromContext(ctx context.Context) *Limits {
+	limits, _ := ctx.Value(limitsKey{}).(*Limits)
+	return limits
+}
+
+// contextError converts the error of a done context, reporting a timeout if the deadline imposed by the
+// limits (rather than the caller's own context) expired
+func (e *evaluator) contextError(err error) error {
+	if e.limits != nil && e.limits.Timeout > 0 && errors.Is(err, context.DeadlineExceeded) && e.parentCtx.Err() == nil {
+		return &LimitError{Limit: ErrEvaluationTimeout, Max: e.limits.Timeout, Err: err}
+	}
+	return err
+}
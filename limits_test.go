@@ -0,0 +1,157 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileLimits(t *testing.T) {
+	env := &Env{Limits: &Limits{MaxExpressionLength: 20, MaxDepth: 3}}
+
+	_, err := CompileWith("1 + 1", env)
+	assert.NoError(t, err)
+
+	_, err = CompileWith(strings.Repeat("1 + ", 10)+"1", env)
+	assert.ErrorIs(t, err, ErrExpressionTooLong)
+	var limitErr *LimitError
+	assert.True(t, errors.As(err, &limitErr))
+	assert.Equal(t, 20, limitErr.Max)
+
+	_, err = CompileWith("(((1)))", env)
+	assert.NoError(t, err)
+
+	_, err = CompileWith("f((((1))))", env)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+	var posErr *PositionError
+	assert.True(t, errors.As(err, &posErr))
+	assert.Equal(t, 4, posErr.Pos)
+
+	_, err = CompileWith("'((((' + 1", env)
+	assert.NoError(t, err)
+
+	// operators nest their operands as deeply as parentheses
+	_, err = CompileWith("f(1 + 2 * 3)", env)
+	assert.NoError(t, err)
+
+	_, err = CompileWith("1 + 2 + 3 + 4 + 5", env)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+	assert.True(t, errors.As(err, &posErr))
+	assert.Equal(t, 0, posErr.Pos)
+
+	_, err = CompileWith("f(g(1 + h(2)))", env)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+
+	// deep nesting is rejected before parsing
+	deep := &Env{Limits: &Limits{MaxDepth: 100}}
+	_, err = CompileWith(strings.Repeat("(", 100000)+"1"+strings.Repeat(")", 100000), deep)
+	assert.ErrorIs(t, err, ErrMaxDepthExceeded)
+}
+
+func TestEvaluationLimits(t *testing.T) {
+	vLookup := func(key string) (any, error) {
+		if key == ".list" {
+			return []any{1., 2., 3.}, nil
+		}
+		return 1., nil
+	}
+	fCall := func(name string, args ...any) (any, error) {
+		if name == "one" {
+			return 1., nil
+		}
+		if name == "repeat" {
+			return strings.Repeat("ab", int(args[0].(float64))), nil
+		}
+		return []string{"a", "b", "c", "d"}, nil
+	}
+
+	for expression, limit := range map[string]error{
+		".a + .b + .c + .d + .e": ErrMaxStepsExceeded,
+		"one(one(one(1)))":       ErrMaxFunctionCalls,
+		"'abcd' + 'efgh'":        ErrStringTooLong,
+		"repeat(5)":              ErrStringTooLong,
+		".list + .list":          ErrArrayTooLong,
+		"letters()":              ErrArrayTooLong,
+	} {
+		env := &Env{
+			Variables: vLookup,
+			Functions: fCall,
+			Limits: &Limits{
+				MaxSteps:         8,
+				MaxFunctionCalls: 2,
+				MaxStringLength:  6,
+				MaxArrayLength:   3,
+			},
+		}
+
+		_, err := EvaluateWith(expression, env)
+		assert.ErrorIs(t, err, limit, expression)
+		var posErr *PositionError
+		assert.True(t, errors.As(err, &posErr), expression)
+	}
+
+	// limits are inherited by child environments
+	env := &Env{Limits: &Limits{MaxStringLength: 3}}
+	_, err := EvaluateWith("'ab' + 'cd'", env.NewChild())
+	assert.ErrorIs(t, err, ErrStringTooLong)
+
+	result, err := EvaluateWith("'ab' + 'c'", env.NewChild())
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", result)
+}
+
+func TestLimitsFromContext(t *testing.T) {
+	env := &Env{
+		FunctionsContext: func(ctx context.Context, name string, args []any, namedArgs map[string]any) (any, error) {
+			n := int(args[0].(float64))
+			// the length is checked before the string is allocated
+			err := LimitsFromContext(ctx).CheckStringLength(n)
+			if err != nil {
+				return nil, err
+			}
+			return strings.Repeat("a", n), nil
+		},
+		Limits: &Limits{MaxStringLength: 10},
+	}
+
+	result, err := EvaluateWith("repeat(10)", env)
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaaaaaaa", result)
+
+	_, err = EvaluateWith("repeat(1e12)", env)
+	assert.ErrorIs(t, err, ErrStringTooLong)
+
+	// evaluations without limits are unbounded
+	env.Limits = nil
+	result, err = EvaluateWith("repeat(11)", env)
+	assert.NoError(t, err)
+	assert.Equal(t, "aaaaaaaaaaa", result)
+	assert.Nil(t, LimitsFromContext(context.Background()))
+	assert.NoError(t, LimitsFromContext(context.Background()).CheckArrayLength(1e9))
+}
+
+func TestEvaluationTimeout(t *testing.T) {
+	env := &Env{
+		Functions: func(name string, args ...any) (any, error) {
+			time.Sleep(5 * time.Millisecond)
+			return 1., nil
+		},
+		Limits: &Limits{Timeout: time.Millisecond},
+	}
+
+	_, err := EvaluateWith("wait() + wait()", env)
+	assert.ErrorIs(t, err, ErrEvaluationTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the caller's own deadline is reported as such, rather than as the timeout limit
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	env.Limits = &Limits{Timeout: time.Hour}
+	_, err = EvaluateWithContext(ctx, "wait() + wait()", env)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrEvaluationTimeout)
+}
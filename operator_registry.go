@@ -0,0 +1,288 @@
+package eval
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// OperatorFunc implements a binary operator, receiving the left and right operand values
+type OperatorFunc func(a any, b any) (any, error)
+
+type Associativity string
+
+const (
+	AssociativityLeft  Associativity = "LEFT"
+	AssociativityRight Associativity = "RIGHT"
+)
+
+// precedence of the builtin operators, higher values bind more tightly.  every builtin operator binds at a
+// level of its own, in the order expressions have always been grouped: `**`, `/`, `*`, `+`, `-`, `==`,
+// `!=`, `>`, `>=`, `<`, `<=`, with `&&` and `||` sharing the lowest level.  each constant below is the level
+// of the loosest operator of its kind (`*`, `-`, `!=` and `<=`), the others binding one step tighter in
+// turn.  all builtin operators are left associative, and operators registered with a precedence of
+// PrecedenceOr are evaluated strictly left to right along with `&&` and `||`.
+const (
+	PrecedenceOr             int = 10
+	PrecedenceComparison     int = 20
+	PrecedenceEquality       int = 30
+	PrecedenceAdditive       int = 40
+	PrecedenceMultiplicative int = 50
+	PrecedenceExponent       int = 60
+)
+
+// Operator describes a binary operator, identified either by a symbol (`~=`) or a keyword (`contains`)
+type Operator struct {
+	Symbol        string
+	Precedence    int
+	Associativity Associativity
+	Func          OperatorFunc
+}
+
+var builtinOperators = []Operator{
+	{Symbol: OperatorExponent, Precedence: PrecedenceExponent, Associativity: AssociativityLeft, Func: ExponentOp},
+	{Symbol: OperatorDivide, Precedence: PrecedenceMultiplicative + 1, Associativity: AssociativityLeft, Func: DivideOp},
+	{Symbol: OperatorMultiply, Precedence: PrecedenceMultiplicative, Associativity: AssociativityLeft, Func: MultiplyOp},
+	{Symbol: OperatorPlus, Precedence: PrecedenceAdditive + 1, Associativity: AssociativityLeft, Func: PlusOp},
+	{Symbol: OperatorMinus, Precedence: PrecedenceAdditive, Associativity: AssociativityLeft, Func: MinusOp},
+	{Symbol: OperatorEquals, Precedence: PrecedenceEquality + 1, Associativity: AssociativityLeft, Func: EqualsOp},
+	{Symbol: OperatorUnequals, Precedence: PrecedenceEquality, Associativity: AssociativityLeft, Func: UnequalsOp},
+	{Symbol: OperatorGreater, Precedence: PrecedenceComparison + 3, Associativity: AssociativityLeft, Func: GreaterThanOp},
+	{Symbol: OperatorGreaterEquals, Precedence: PrecedenceComparison + 2, Associativity: AssociativityLeft, Func: GreaterThanEqualsOp},
+	{Symbol: OperatorLess, Precedence: PrecedenceComparison + 1, Associativity: AssociativityLeft, Func: LessThanOp},
+	{Symbol: OperatorLessEquals, Precedence: PrecedenceComparison, Associativity: AssociativityLeft, Func: LessThanEqualsOp},
+	{Symbol: OperatorAnd, Precedence: PrecedenceOr, Associativity: AssociativityLeft, Func: AndOp},
+	{Symbol: OperatorOr, Precedence: PrecedenceOr, Associativity: AssociativityLeft, Func: OrOp},
+}
+
+// symbolChars are the characters from which operator symbols may be composed
+var symbolChars = map[byte]struct{}{
+	Equals:      {},
+	Exclamation: {},
+	GreaterThan: {},
+	LessThan:    {},
+	Ampersand:   {},
+	Pipe:        {},
+	Plus:        {},
+	Minus:       {},
+	Multiply:    {},
+	Divide:      {},
+	'~':         {},
+	'^':         {},
+	'%':         {},
+	'@':         {},
+	'#':         {},
+	'?':         {},
+	':':         {},
+}
+
+var keywordParser = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z_0-9]*$`)
+
+// OperatorRegistry holds the set of operators known to the tokenizer and evaluator.  a registry returned by
+// NewOperatorRegistry contains all builtin operators, to which custom operators may be added.  operators
+// must be registered prior to compiling any program which makes use of them.
+type OperatorRegistry struct {
+	operators map[string]*Operator
+	// chars holds all characters appearing in symbolic operators, as well as the separator and assignment
+	chars map[byte]struct{}
+	// levels holds the distinct precedences (above PrecedenceOr) of all registered operators, highest first
+	levels []int
+}
+
+var builtinRegistry = NewOperatorRegistry()
+
+// NewOperatorRegistry returns a registry containing the builtin operators
+func NewOperatorRegistry() *OperatorRegistry {
+	r := &OperatorRegistry{
+		operators: map[string]*Operator{},
+		chars: map[byte]struct{}{
+			Comma:  {},
+			Equals: {},
+		},
+	}
+
+	for _, op := range builtinOperators {
+		err := r.Register(op)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return r
+}
+
+// Register adds an operator to the registry.  the symbol must either be composed entirely of operator
+// characters (`=!<>&|+-*/~^%@#?:`), or be a keyword made up of alpha-numeric characters and underscores.
+// the precedence may not be lower than PrecedenceOr, and all operators sharing a precedence must share the
+// same associativity.
+func (r *OperatorRegistry) Register(op Operator) error {
+	if op.Func == nil {
+		return fmt.Errorf("operator %s has no implementation", op.Symbol)
+	}
+
+	if op.Symbol == Separator || op.Symbol == Assignment || op.Symbol == "true" || op.Symbol == "false" {
+		return fmt.Errorf("%s is reserved and cannot be registered as an operator", op.Symbol)
+	}
+
+	if _, ok := r.operators[op.Symbol]; ok {
+		return fmt.Errorf("operator %s is already registered", op.Symbol)
+	}
+
+	if !isSymbol(op.Symbol) && !keywordParser.MatchString(op.Symbol) {
+		return fmt.Errorf("invalid operator %q, must be a symbol or a keyword", op.Symbol)
+	}
+
+	if op.Precedence < PrecedenceOr {
+		return fmt.Errorf("operator %s has precedence %d, which is lower than the minimum of %d", op.Symbol, op.Precedence, PrecedenceOr)
+	}
+
+	switch op.Associativity {
+	case "":
+		op.Associativity = AssociativityLeft
+	case AssociativityLeft, AssociativityRight:
+	default:
+		return fmt.Errorf("operator %s has unknown associativity %s", op.Symbol, op.Associativity)
+	}
+
+	if op.Precedence == PrecedenceOr && op.Associativity != AssociativityLeft {
+		return fmt.Errorf("operator %s must be left associative at precedence %d", op.Symbol, op.Precedence)
+	}
+
+	for _, existing := range r.operators {
+		if existing.Precedence == op.Precedence && existing.Associativity != op.Associativity {
+			return fmt.Errorf("operator %s is %s associative, conflicting with %s at precedence %d", op.Symbol, op.Associativity, existing.Symbol, op.Precedence)
+		}
+	}
+
+	r.operators[op.Symbol] = &op
+	if isSymbol(op.Symbol) {
+		for i := range len(op.Symbol) {
+			r.chars[op.Symbol[i]] = struct{}{}
+		}
+	}
+
+	if op.Precedence > PrecedenceOr && !slices.Contains(r.levels, op.Precedence) {
+		r.levels = append(r.levels, op.Precedence)
+		slices.Sort(r.levels)
+		slices.Reverse(r.levels)
+	}
+
+	return nil
+}
+
+// Lookup returns the operator registered under the symbol or keyword
+func (r *OperatorRegistry) Lookup(symbol string) (*Operator, bool) {
+	op, ok := r.operators[symbol]
+	return op, ok
+}
+
+// Compile parses an expression into a program using the operators held by the registry, folding its
+// constant subexpressions (see CompileWith to disable this)
+func (r *OperatorRegistry) Compile(expression string) (*Program, error) {
+	program, err := r.parse(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	program.fold(nil)
+	return program, nil
+}
+
+// parse parses an expression into a program without any optimization
+func (r *OperatorRegistry) parse(expression string) (*Program, error) {
+	root, err := r.tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Program{
+		expression: expression,
+		root:       root,
+		operators:  r,
+	}, nil
+}
+
+// isOperatorChar returns true if the character may form part of a symbolic operator, separator or assignment
+func (r *OperatorRegistry) isOperatorChar(c byte) bool {
+	_, ok := r.chars[c]
+	return ok
+}
+
+// isOperand returns true if the token produces a value, as opposed to being an operator or other punctuation
+func isOperand(t *Token) bool {
+	switch t.Type {
+	case TokenTypeOperator, TokenTypeSeparator, TokenTypeAssignment, TokenTypeSpread:
+		return false
+	default:
+		return true
+	}
+}
+
+func isSymbol(text string) bool {
+	if len(text) == 0 {
+		return false
+	}
+
+	for i := range len(text) {
+		if _, ok := symbolChars[text[i]]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// groupByPrecedence wraps each `operand operator operand` sequence into a group token, from the highest
+// precedence level to the lowest, honouring the associativity of each level.  operators at PrecedenceOr are
+// left ungrouped, to be evaluated left to right by the enclosing group.
+func (r *OperatorRegistry) groupByPrecedence(tokens []*Token) []*Token {
+	for _, level := range r.levels {
+		isLevelOperator := func(t *Token) bool {
+			if t.Type != TokenTypeOperator {
+				return false
+			}
+			op, ok := r.operators[t.Text]
+			return ok && op.Precedence == level
+		}
+
+		var rightAssociative bool
+		for _, op := range r.operators {
+			if op.Precedence == level {
+				rightAssociative = op.Associativity == AssociativityRight
+				break
+			}
+		}
+
+		if rightAssociative {
+			slices.Reverse(tokens)
+		}
+
+		var grouped []*Token
+		i := 0
+		for i < len(tokens) {
+			last := len(grouped) - 1
+			if last >= 0 && i+1 < len(tokens) && isLevelOperator(tokens[i]) && isOperand(grouped[last]) && isOperand(tokens[i+1]) {
+				left, right := grouped[last], tokens[i+1]
+				if rightAssociative {
+					left, right = right, left
+				}
+				grouped[last] = &Token{
+					Type:   TokenTypeGroup,
+					Tokens: []*Token{left, tokens[i], right},
+					Pos:    left.Pos,
+				}
+				i += 2
+				continue
+			}
+
+			grouped = append(grouped, tokens[i])
+			i++
+		}
+
+		if rightAssociative {
+			slices.Reverse(grouped)
+		}
+		tokens = grouped
+	}
+
+	return tokens
+}
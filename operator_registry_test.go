@@ -0,0 +1,111 @@
+package eval
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func containsOp(a any, b any) (any, error) {
+	aStr, aOk := a.(string)
+	bStr, bOk := b.(string)
+	if !aOk || !bOk {
+		return nil, fmt.Errorf("contains requires strings")
+	}
+	return strings.Contains(aStr, bStr), nil
+}
+
+func fuzzyOp(a any, b any) (any, error) {
+	return strings.EqualFold(AsString(a), AsString(b)), nil
+}
+
+func TestOperatorRegistryCustomOperators(t *testing.T) {
+	registry := NewOperatorRegistry()
+	err := registry.Register(Operator{Symbol: "contains", Precedence: PrecedenceComparison, Func: containsOp})
+	assert.NoError(t, err)
+	err = registry.Register(Operator{Symbol: "~=", Precedence: PrecedenceEquality, Func: fuzzyOp})
+	assert.NoError(t, err)
+
+	program, err := registry.Compile("'hello world' contains 'lo' + ' w' && 'ABC' ~= 'abc'")
+	assert.NoError(t, err)
+	result, err := program.Evaluate(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+
+	// the builtin registry is unaffected
+	_, err = Evaluate("'ABC' ~= 'abc'", nil, nil)
+	assert.Error(t, err)
+	_, err = Evaluate("'hello world' contains 'lo'", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestOperatorRegistryRightAssociative(t *testing.T) {
+	registry := NewOperatorRegistry()
+	err := registry.Register(Operator{
+		Symbol:        "^^",
+		Precedence:    PrecedenceExponent + 10,
+		Associativity: AssociativityRight,
+		Func: func(a any, b any) (any, error) {
+			return fmt.Sprintf("(%v^^%v)", a, b), nil
+		},
+	})
+	assert.NoError(t, err)
+
+	program, err := registry.Compile("a ^^ b ^^ c")
+	assert.NoError(t, err)
+	result, err := program.Evaluate(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "(a^^(b^^c))", result)
+}
+
+func TestOperatorRegistryRegistrationErrors(t *testing.T) {
+	registry := NewOperatorRegistry()
+	assert.Error(t, registry.Register(Operator{Symbol: "+", Precedence: PrecedenceAdditive, Func: PlusOp}))
+	assert.Error(t, registry.Register(Operator{Symbol: "=", Precedence: PrecedenceEquality, Func: EqualsOp}))
+	assert.Error(t, registry.Register(Operator{Symbol: "a.b", Precedence: PrecedenceEquality, Func: EqualsOp}))
+	assert.Error(t, registry.Register(Operator{Symbol: "~", Precedence: 5, Func: EqualsOp}))
+	assert.Error(t, registry.Register(Operator{Symbol: "~", Precedence: PrecedenceEquality}))
+	assert.Error(t, registry.Register(Operator{Symbol: "~", Precedence: PrecedenceAdditive, Associativity: AssociativityRight, Func: EqualsOp}))
+	assert.NoError(t, registry.Register(Operator{Symbol: "~", Precedence: PrecedenceAdditive, Func: EqualsOp}))
+}
+
+func TestBuiltinPrecedence(t *testing.T) {
+	for expression, expected := range map[string]any{
+		"10 - 2 + 3":             5.,
+		"10 - 2 - 3":             5.,
+		"(10 - 2) + 3":           11.,
+		"2 * 3 * 4 + 1":          25.,
+		"12 / 2 * 3":             18.,
+		"2 ** 3 ** 2":            64.,
+		"1 + 2 * 3 ** 2":         19.,
+		"1 == 1 > false":         true,
+		"true > 1 == 2":          true,
+		"false || true && false": false,
+		"true || false && false": false,
+		"true && false || true":  true,
+	} {
+		result, err := Evaluate(expression, nil, nil)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestChainedOperators(t *testing.T) {
+	// v0.0.6 grouped a chain of the same operator in pairs, evaluating `1 - 2 - 3 - 4` as
+	// `(1 - 2) - (3 - 4)`, chains are now grouped from the left
+	for expression, expected := range map[string]any{
+		"1 - 2 - 3 - 4":          -8.,
+		"100 / 10 / 5 / 2":       1.,
+		"1 - 2 - 3 - 4 - 5":      -13.,
+		"2 ** 2 ** 2 ** 2":       256.,
+		"1 - 2 + 3 - 4 + 5":      -13.,
+		"64 / 4 * 2 / 2 * 2":     32.,
+		"1 == 1 == true == true": true,
+	} {
+		result, err := Evaluate(expression, nil, nil)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
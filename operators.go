@@ -1,235 +1,299 @@
 package eval
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"math"
+	"reflect"
+	"strings"
+	"time"
 )
 
-func EqualsOp(a any, b any) (any, error) {
-	switch aT := a.(type) {
-	case string:
-		switch bT := b.(type) {
-		case string:
-			return aT == bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for == comparison", a, b)
-		}
-	case float64:
-		switch bT := b.(type) {
-		case float64:
-			return aT == bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for == comparison", a, b)
-		}
-	case bool:
-		switch bT := b.(type) {
-		case bool:
-			return aT == bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for == comparison", a, b)
+// DeepEqual reports whether a and b are structurally equal.  arrays ([]any and the typed slices understood
+// by subscripting) are equal when they hold equal elements in the same order, mappings are equal when they
+// hold the same keys with equal values.  numeric values of any kind are compared as float64, thus []int{1}
+// is equal to []any{1.}.  values of differing types are never equal, unless one of them implements Equaler,
+// and values whose Equaler fails are not equal.
+func DeepEqual(a any, b any) bool {
+	eq, err := deepEqual(a, b)
+	return eq && err == nil
+}
+
+// deepEqual compares a and b structurally as described by DeepEqual, returning the error of any Equaler
+// which fails
+func deepEqual(a any, b any) (bool, error) {
+	if eq, ok, err := equalOverload(a, b); ok {
+		return eq, err
+	}
+
+	a = CastToFloat64IfApplicable(a)
+	b = CastToFloat64IfApplicable(b)
+
+	if aItems, ok := toArray(a); ok {
+		bItems, ok := toArray(b)
+		if !ok || len(aItems) != len(bItems) {
+			return false, nil
 		}
 
-	default:
-		return nil, fmt.Errorf("%v and %v are incompatible types for == comparison", a, b)
+		for i := range aItems {
+			eq, err := deepEqual(aItems[i], bItems[i])
+			if !eq || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
 	}
-}
 
-func UnequalsOp(a any, b any) (any, error) {
 	switch aT := a.(type) {
-	case string:
-		switch bT := b.(type) {
-		case string:
-			return aT != bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for != comparison", a, b)
+	case map[string]any:
+		bT, ok := b.(map[string]any)
+		if !ok || len(aT) != len(bT) {
+			return false, nil
 		}
-	case float64:
-		switch bT := b.(type) {
-		case float64:
-			return aT != bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for != comparison", a, b)
+
+		for k, v := range aT {
+			bv, ok := bT[k]
+			if !ok {
+				return false, nil
+			}
+			eq, err := deepEqual(v, bv)
+			if !eq || err != nil {
+				return false, err
+			}
 		}
-	case bool:
-		switch bT := b.(type) {
-		case bool:
-			return aT != bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for != comparison", a, b)
+		return true, nil
+	case time.Time:
+		// as at the top level, a string equals a time when it is the same instant in RFC 3339 form
+		bT, ok := asTime(b)
+		return ok && aT.Equal(bT), nil
+	case string:
+		if bT, ok := b.(time.Time); ok {
+			aTime, ok := asTime(aT)
+			return ok && aTime.Equal(bT), nil
 		}
-
+		return a == b, nil
+	case float64, bool, time.Duration, nil:
+		return a == b, nil
 	default:
-		return nil, fmt.Errorf("%v and %v are incompatible types for != comparison", a, b)
+		return reflect.DeepEqual(a, b), nil
 	}
 }
 
-func GreaterThanOp(a any, b any) (any, error) {
-	switch aT := a.(type) {
-	case string:
-		switch bT := b.(type) {
-		case string:
-			return aT > bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for > comparison", a, b)
-		}
-	case float64:
-		switch bT := b.(type) {
-		case float64:
-			return aT > bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for > comparison", a, b)
-		}
-	default:
-		return nil, fmt.Errorf("%v and %v are incompatible types for > comparison", a, b)
+// equalsFor compares a and b on behalf of the == and != operators, errors returned by an Equaler are passed
+// through, whereas builtin incompatibilities are reported against the operator
+func equalsFor(a any, b any, operator string) (bool, error) {
+	if eq, ok, err := equalOverload(a, b); ok {
+		return eq, err
+	}
+
+	eq, ok, err := equals(a, b)
+	if !ok {
+		return false, fmt.Errorf("%v and %v are incompatible types for %s comparison", a, b, operator)
 	}
+	return eq, err
 }
 
-func GreaterThanEqualsOp(a any, b any) (any, error) {
+// equals compares a and b for the == and != operators, the second return value is false when the
+// types cannot be compared with one another
+func equals(a any, b any) (bool, bool, error) {
+	a = CastToFloat64IfApplicable(a)
+	b = CastToFloat64IfApplicable(b)
+
 	switch aT := a.(type) {
 	case string:
-		switch bT := b.(type) {
-		case string:
-			return aT >= bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for >= comparison", a, b)
+		if bT, ok := b.(time.Time); ok {
+			aTime, ok := asTime(aT)
+			return ok && aTime.Equal(bT), ok, nil
 		}
+		bT, ok := b.(string)
+		return ok && aT == bT, ok, nil
+	case time.Time:
+		bT, ok := asTime(b)
+		return ok && aT.Equal(bT), ok, nil
+	case time.Duration:
+		bT, ok := b.(time.Duration)
+		return ok && aT == bT, ok, nil
 	case float64:
-		switch bT := b.(type) {
-		case float64:
-			return aT >= bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for >= comparison", a, b)
+		bT, ok := b.(float64)
+		return ok && aT == bT, ok, nil
+	case bool:
+		bT, ok := b.(bool)
+		return ok && aT == bT, ok, nil
+	case map[string]any:
+		if _, ok := b.(map[string]any); !ok {
+			return false, false, nil
 		}
+		eq, err := deepEqual(a, b)
+		return eq, true, err
 	default:
-		return nil, fmt.Errorf("%v and %v are incompatible types for >= comparison", a, b)
+		if _, ok := toArray(a); !ok {
+			return false, false, nil
+		}
+		if _, ok := toArray(b); !ok {
+			return false, false, nil
+		}
+		eq, err := deepEqual(a, b)
+		return eq, true, err
 	}
 }
 
-func LessThanOp(a any, b any) (any, error) {
-	switch aT := a.(type) {
-	case string:
-		switch bT := b.(type) {
-		case string:
-			return aT < bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for < comparison", a, b)
-		}
-	case float64:
-		switch bT := b.(type) {
-		case float64:
-			return aT < bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for < comparison", a, b)
-		}
-	default:
-		return nil, fmt.Errorf("%v and %v are incompatible types for < comparison", a, b)
+func EqualsOp(a any, b any) (any, error) {
+	eq, err := equalsFor(a, b, "==")
+	if err != nil {
+		return nil, err
 	}
+	return eq, nil
 }
 
-func LessThanEqualsOp(a any, b any) (any, error) {
-	switch aT := a.(type) {
-	case string:
-		switch bT := b.(type) {
-		case string:
-			return aT <= bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for <= comparison", a, b)
-		}
-	case float64:
-		switch bT := b.(type) {
-		case float64:
-			return aT <= bT, nil
-		default:
-			return nil, fmt.Errorf("%v and %v are incompatible types for <= comparison", a, b)
-		}
-	default:
-		return nil, fmt.Errorf("%v and %v are incompatible types for <= comparison", a, b)
+func UnequalsOp(a any, b any) (any, error) {
+	eq, err := equalsFor(a, b, "!=")
+	if err != nil {
+		return nil, err
 	}
+	return !eq, nil
 }
 
-func AndOp(a any, b any) (any, error) {
-	var aIsTrue bool
-	var bIsTrue bool
+// ErrUnordered is returned when comparing NaN with the ordering operators, as it has no position in the
+// ordering
+var ErrUnordered = errors.New("NaN cannot be ordered")
+
+// Compare defines the ordering used by the <, <=, > and >= operators, returning a negative number when a
+// orders before b, zero when they are equivalent and a positive number when a orders after b.  the ordering
+// is defined as follows, any other combination of types is incompatible and returns an error:
+//   - values implementing Comparer (either operand) order themselves
+//   - numbers of any kind are compared as float64, NaN is not ordered and returns ErrUnordered, in agreement
+//     with == which never considers NaN equal to anything (including NaN)
+//   - strings are compared bytewise (ASCII/UTF-8 value determines weight)
+//   - booleans order false before true
+//   - times are compared chronologically, and may be compared against RFC 3339 formatted strings
+//   - durations are compared by length
+//   - arrays are compared lexicographically, element by element using these same rules, where an array
+//     which is a prefix of another orders first
+func Compare(a any, b any) (int, error) {
+	if c, ok, err := compareOverload(a, b); ok {
+		return c, err
+	}
+
+	a = CastToFloat64IfApplicable(a)
+	b = CastToFloat64IfApplicable(b)
+
 	switch aT := a.(type) {
 	case string:
-		aIsTrue = len(aT) > 0
+		if bT, ok := b.(string); ok {
+			return strings.Compare(aT, bT), nil
+		}
+		if bT, ok := b.(time.Time); ok {
+			if aTime, ok := asTime(aT); ok {
+				return aTime.Compare(bT), nil
+			}
+		}
+	case time.Time:
+		if bT, ok := asTime(b); ok {
+			return aT.Compare(bT), nil
+		}
+	case time.Duration:
+		if bT, ok := b.(time.Duration); ok {
+			return cmp.Compare(aT, bT), nil
+		}
 	case float64:
-		aIsTrue = aT != 0
+		if bT, ok := b.(float64); ok {
+			if math.IsNaN(aT) || math.IsNaN(bT) {
+				return 0, ErrUnordered
+			}
+			return cmp.Compare(aT, bT), nil
+		}
 	case bool:
-		aIsTrue = a == true
-	case []any:
-		aIsTrue = len(aT) > 0
-	case map[string]any:
-		aIsTrue = len(aT) > 0
+		if bT, ok := b.(bool); ok {
+			switch {
+			case aT == bT:
+				return 0, nil
+			case aT:
+				return 1, nil
+			default:
+				return -1, nil
+			}
+		}
 	default:
-		aIsTrue = false
+		aItems, aOk := toArray(a)
+		bItems, bOk := toArray(b)
+		if aOk && bOk {
+			for i := range min(len(aItems), len(bItems)) {
+				c, err := Compare(aItems[i], bItems[i])
+				if err != nil {
+					return 0, err
+				}
+				if c != 0 {
+					return c, nil
+				}
+			}
+			return cmp.Compare(len(aItems), len(bItems)), nil
+		}
 	}
 
-	switch bT := b.(type) {
-	case string:
-		bIsTrue = len(bT) > 0
-	case float64:
-		bIsTrue = bT != 0
-	case bool:
-		bIsTrue = b == true
-	case []any:
-		aIsTrue = len(bT) > 0
-	case map[string]any:
-		aIsTrue = len(bT) > 0
-	default:
-		bIsTrue = false
+	return 0, fmt.Errorf("%v and %v are incompatible types for comparison", a, b)
+}
+
+// compareFor compares a and b on behalf of an ordering operator, errors returned by a Comparer are passed
+// through, whereas builtin incompatibilities are reported against the operator
+func compareFor(a any, b any, operator string) (int, error) {
+	if c, ok, err := compareOverload(a, b); ok {
+		return c, err
 	}
 
-	if aIsTrue && bIsTrue {
-		return b, nil
+	c, err := Compare(a, b)
+	switch {
+	case errors.Is(err, ErrUnordered):
+		return 0, fmt.Errorf("%s comparison: %w", operator, err)
+	case err != nil:
+		return 0, fmt.Errorf("%v and %v are incompatible types for %s comparison", a, b, operator)
 	}
+	return c, nil
+}
 
-	if !aIsTrue {
-		return a, nil
+func GreaterThanOp(a any, b any) (any, error) {
+	c, err := compareFor(a, b, ">")
+	if err != nil {
+		return nil, err
 	}
+	return c > 0, nil
+}
 
-	return b, nil
+func GreaterThanEqualsOp(a any, b any) (any, error) {
+	c, err := compareFor(a, b, ">=")
+	if err != nil {
+		return nil, err
+	}
+	return c >= 0, nil
 }
 
-func OrOp(a any, b any) (any, error) {
-	var aIsTrue bool
-	var bIsTrue bool
-	switch aT := a.(type) {
-	case string:
-		aIsTrue = len(aT) > 0
-	case float64:
-		aIsTrue = aT != 0
-	case bool:
-		aIsTrue = a == true
-	case []any:
-		aIsTrue = len(aT) > 0
-	case map[string]any:
-		aIsTrue = len(aT) > 0
-	default:
-		aIsTrue = false
+func LessThanOp(a any, b any) (any, error) {
+	c, err := compareFor(a, b, "<")
+	if err != nil {
+		return nil, err
 	}
+	return c < 0, nil
+}
 
-	switch bT := b.(type) {
-	case string:
-		bIsTrue = len(bT) > 0
-	case float64:
-		bIsTrue = bT != 0
-	case bool:
-		bIsTrue = b == true
-	case []any:
-		aIsTrue = len(bT) > 0
-	case map[string]any:
-		aIsTrue = len(bT) > 0
-	default:
-		bIsTrue = false
+func LessThanEqualsOp(a any, b any) (any, error) {
+	c, err := compareFor(a, b, "<=")
+	if err != nil {
+		return nil, err
 	}
+	return c <= 0, nil
+}
 
-	if aIsTrue {
+func AndOp(a any, b any) (any, error) {
+	if !IsTruthy(a) {
 		return a, nil
 	}
 
-	if bIsTrue {
-		return b, nil
+	return b, nil
+}
+
+func OrOp(a any, b any) (any, error) {
+	if IsTruthy(a) {
+		return a, nil
 	}
 
 	return b, nil
@@ -237,6 +301,8 @@ func OrOp(a any, b any) (any, error) {
 
 func PlusOp(a any, b any) (any, error) {
 	switch aT := a.(type) {
+	case Adder:
+		return aT.Add(b)
 	case string:
 		switch bT := b.(type) {
 		case string:
@@ -258,6 +324,22 @@ func PlusOp(a any, b any) (any, error) {
 		default:
 			return nil, fmt.Errorf("%v and %v are incompatible types for addition/concatenation", a, b)
 		}
+	case time.Time:
+		switch bT := b.(type) {
+		case time.Duration:
+			return aT.Add(bT), nil
+		default:
+			return nil, fmt.Errorf("%v and %v are incompatible types for addition/concatenation", a, b)
+		}
+	case time.Duration:
+		switch bT := b.(type) {
+		case time.Duration:
+			return addDurations(aT, bT)
+		case time.Time:
+			return bT.Add(aT), nil
+		default:
+			return nil, fmt.Errorf("%v and %v are incompatible types for addition/concatenation", a, b)
+		}
 	default:
 		return nil, fmt.Errorf("%v and %v are incompatible types for addition/concatenation", a, b)
 	}
@@ -265,6 +347,8 @@ func PlusOp(a any, b any) (any, error) {
 
 func MinusOp(a any, b any) (any, error) {
 	switch aT := a.(type) {
+	case Subtractor:
+		return aT.Subtract(b)
 	case float64:
 		switch bT := b.(type) {
 		case float64:
@@ -272,6 +356,22 @@ func MinusOp(a any, b any) (any, error) {
 		default:
 			return nil, fmt.Errorf("%v and %v are incompatible types for subtraction", a, b)
 		}
+	case time.Time:
+		switch bT := b.(type) {
+		case time.Duration:
+			return aT.Add(-bT), nil
+		case time.Time:
+			return aT.Sub(bT), nil
+		default:
+			return nil, fmt.Errorf("%v and %v are incompatible types for subtraction", a, b)
+		}
+	case time.Duration:
+		switch bT := b.(type) {
+		case time.Duration:
+			return subtractDurations(aT, bT)
+		default:
+			return nil, fmt.Errorf("%v and %v are incompatible types for subtraction", a, b)
+		}
 	default:
 		return nil, fmt.Errorf("%v and %v are incompatible types for subtraction", a, b)
 	}
@@ -279,10 +379,21 @@ func MinusOp(a any, b any) (any, error) {
 
 func MultiplyOp(a any, b any) (any, error) {
 	switch aT := a.(type) {
+	case Multiplier:
+		return aT.Multiply(b)
 	case float64:
 		switch bT := b.(type) {
 		case float64:
 			return float64(aT * bT), nil
+		case time.Duration:
+			return toDuration(aT*float64(bT), "multiplication")
+		default:
+			return nil, fmt.Errorf("%v and %v are incompatible types for multiplication", a, b)
+		}
+	case time.Duration:
+		switch bT := b.(type) {
+		case float64:
+			return toDuration(float64(aT)*bT, "multiplication")
 		default:
 			return nil, fmt.Errorf("%v and %v are incompatible types for multiplication", a, b)
 		}
@@ -307,6 +418,8 @@ func ExponentOp(a any, b any) (any, error) {
 
 func DivideOp(a any, b any) (any, error) {
 	switch aT := a.(type) {
+	case Divider:
+		return aT.Divide(b)
 	case float64:
 		switch bT := b.(type) {
 		case float64:
@@ -317,6 +430,21 @@ func DivideOp(a any, b any) (any, error) {
 		default:
 			return nil, fmt.Errorf("%v and %v are incompatible types for division", a, b)
 		}
+	case time.Duration:
+		switch bT := b.(type) {
+		case float64:
+			if bT == 0 {
+				return nil, fmt.Errorf("division by zero error")
+			}
+			return toDuration(float64(aT)/bT, "division")
+		case time.Duration:
+			if bT == 0 {
+				return nil, fmt.Errorf("division by zero error")
+			}
+			return float64(aT) / float64(bT), nil
+		default:
+			return nil, fmt.Errorf("%v and %v are incompatible types for division", a, b)
+		}
 	default:
 		return nil, fmt.Errorf("%v and %v are incompatible types for division", a, b)
 	}
@@ -1,7 +1,9 @@
 package eval
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -323,3 +325,222 @@ func TestDivideOperator(t *testing.T) {
 	_, err = DivideOp(9.0, 0.0)
 	assert.Error(t, err)
 }
+
+func TestEqualsOperatorStructural(t *testing.T) {
+	result, err := EqualsOp([]any{1., "a", []any{true}}, []any{1., "a", []any{true}})
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = EqualsOp([]any{1., 2.}, []any{2., 1.})
+	assert.NoError(t, err)
+	assert.False(t, result.(bool))
+
+	result, err = EqualsOp([]int{1, 2}, []any{1., 2.})
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = EqualsOp([]string{"a"}, []any{"a", "b"})
+	assert.NoError(t, err)
+	assert.False(t, result.(bool))
+
+	result, err = EqualsOp(map[string]any{"a": []any{1}, "b": "c"}, map[string]any{"b": "c", "a": []int64{1}})
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = EqualsOp(map[string]any{"a": 1.}, map[string]any{"a": "1"})
+	assert.NoError(t, err)
+	assert.False(t, result.(bool))
+
+	result, err = UnequalsOp([]any{"a"}, []any{"b"})
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = EqualsOp(1, 1.)
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	_, err = EqualsOp([]any{1.}, map[string]any{})
+	assert.Error(t, err)
+
+	_, err = UnequalsOp([]any{1.}, 1.)
+	assert.Error(t, err)
+
+	// nested times equal strings holding the same instant, as they do at the top level
+	tm := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err = EqualsOp([]any{tm}, []any{"2024-01-01T00:00:00Z"})
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = EqualsOp(map[string]any{"at": "2024-01-01T02:00:00+02:00"}, map[string]any{"at": tm})
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = EqualsOp([]any{tm}, []any{"2024-01-02T00:00:00Z"})
+	assert.NoError(t, err)
+	assert.False(t, result.(bool))
+
+	result, err = EqualsOp([]any{tm}, []any{"yesterday"})
+	assert.NoError(t, err)
+	assert.False(t, result.(bool))
+}
+
+func TestCompare(t *testing.T) {
+	c, err := Compare([]any{1., 2., 3.}, []any{1., 10., 0.})
+	assert.NoError(t, err)
+	assert.Equal(t, -1, c)
+
+	c, err = Compare([]any{1., 2.}, []any{1., 2., 0.})
+	assert.NoError(t, err)
+	assert.Equal(t, -1, c)
+
+	c, err = Compare([]int{1, 2}, []any{1., 2.})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, c)
+
+	c, err = Compare([]string{"b"}, []any{"a", "z"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c)
+
+	c, err = Compare(true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c)
+
+	c, err = Compare(int8(3), uint64(3))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, c)
+
+	_, err = Compare([]any{1.}, []any{"a"})
+	assert.Error(t, err)
+
+	_, err = Compare(true, 1.)
+	assert.Error(t, err)
+
+	_, err = Compare(map[string]any{}, map[string]any{})
+	assert.Error(t, err)
+}
+
+func TestCompareNaN(t *testing.T) {
+	nan := math.NaN()
+
+	_, err := Compare(nan, nan)
+	assert.ErrorIs(t, err, ErrUnordered)
+	_, err = Compare(1., nan)
+	assert.ErrorIs(t, err, ErrUnordered)
+	_, err = Compare([]any{1., nan}, []any{1., 2.})
+	assert.ErrorIs(t, err, ErrUnordered)
+
+	_, err = LessThanOp(nan, 1.)
+	assert.ErrorIs(t, err, ErrUnordered)
+	assert.EqualError(t, err, "< comparison: NaN cannot be ordered")
+	_, err = GreaterThanEqualsOp(float32(nan), nan)
+	assert.ErrorIs(t, err, ErrUnordered)
+
+	// NaN is never equal, even to itself
+	result, err := EqualsOp(nan, nan)
+	assert.NoError(t, err)
+	assert.Equal(t, false, result)
+	result, err = UnequalsOp(nan, nan)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+	assert.False(t, DeepEqual([]any{nan}, []any{nan}))
+}
+
+func TestOrderingOperatorsMixedTypes(t *testing.T) {
+	result, err := LessThanOp([]any{1., 2., 3.}, []any{1., 10., 0.})
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = GreaterThanEqualsOp(int64(5), 5.)
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = GreaterThanOp(float32(1.5), uint8(1))
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = LessThanEqualsOp(false, true)
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	_, err = LessThanOp([]any{1.}, "a")
+	assert.Error(t, err)
+}
+
+func TestTimeAndDurationOperators(t *testing.T) {
+	t1 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	t2 := t1.Add(90 * time.Minute)
+
+	result, err := MinusOp(t2, t1)
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Minute, result)
+
+	result, err = PlusOp(t1, 90*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, t2, result)
+
+	result, err = PlusOp(90*time.Minute, t1)
+	assert.NoError(t, err)
+	assert.Equal(t, t2, result)
+
+	result, err = MinusOp(t2, 90*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, t1, result)
+
+	result, err = MultiplyOp(time.Minute, 2.)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, result)
+
+	result, err = DivideOp(time.Hour, 30*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 2., result)
+
+	result, err = LessThanOp(t1, t2)
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = GreaterThanOp(time.Hour, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = EqualsOp(t1, "2025-01-01T12:00:00Z")
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	result, err = LessThanOp("2024-12-31T23:59:59Z", t1)
+	assert.NoError(t, err)
+	assert.True(t, result.(bool))
+
+	_, err = PlusOp(t1, t2)
+	assert.Error(t, err)
+
+	_, err = LessThanOp(t1, time.Hour)
+	assert.Error(t, err)
+
+	_, err = EqualsOp(t1, "yesterday")
+	assert.Error(t, err)
+}
+
+func TestDurationOverflow(t *testing.T) {
+	maxDuration := time.Duration(math.MaxInt64)
+
+	_, err := MultiplyOp(maxDuration, 2.)
+	assert.ErrorContains(t, err, "duration multiplication overflows")
+	_, err = MultiplyOp(-1e10, time.Hour)
+	assert.ErrorContains(t, err, "duration multiplication overflows")
+	_, err = MultiplyOp(time.Hour, math.NaN())
+	assert.Error(t, err)
+	_, err = DivideOp(time.Hour, 1e-10)
+	assert.ErrorContains(t, err, "duration division overflows")
+	_, err = PlusOp(maxDuration, time.Nanosecond)
+	assert.ErrorContains(t, err, "duration addition overflows")
+	_, err = MinusOp(time.Duration(math.MinInt64), time.Nanosecond)
+	assert.ErrorContains(t, err, "duration subtraction overflows")
+
+	result, err := MultiplyOp(1e6, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1e6*time.Hour, result)
+
+	result, err = MinusOp(-time.Hour, -maxDuration)
+	assert.NoError(t, err)
+	assert.Equal(t, maxDuration-time.Hour, result)
+}
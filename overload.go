@@ -0,0 +1,85 @@
+package eval
+
+// the interfaces below allow host-defined value types (returned from variable lookups or functions) to
+// participate in the builtin operators.  operators dispatch to these interfaces before falling back to
+// their builtin type rules.
+
+// Adder is implemented by types supporting the + operator when appearing as the left operand
+type Adder interface {
+	Add(other any) (any, error)
+}
+
+// Subtractor is implemented by types supporting the - operator when appearing as the left operand
+type Subtractor interface {
+	Subtract(other any) (any, error)
+}
+
+// Multiplier is implemented by types supporting the * operator when appearing as the left operand
+type Multiplier interface {
+	Multiply(other any) (any, error)
+}
+
+// Divider is implemented by types supporting the / operator when appearing as the left operand
+type Divider interface {
+	Divide(other any) (any, error)
+}
+
+// Comparer is implemented by types supporting the <, <=, > and >= operators.  Compare returns a negative
+// number when the value orders before other, zero when equivalent and a positive number when it orders
+// after.  the value may appear as either operand, when on the right the result is inverted.
+type Comparer interface {
+	Compare(other any) (int, error)
+}
+
+// Equaler is implemented by types supporting the == and != operators, and structural comparison within
+// arrays and mappings.  the value may appear as either operand.
+type Equaler interface {
+	Equal(other any) (bool, error)
+}
+
+// Truthy is implemented by types which define their own truthiness, as used by IsTruthy, && and ||
+type Truthy interface {
+	Truthy() bool
+}
+
+// equalOverload dispatches to an Equaler implemented by either operand, preferring the left and falling
+// back to the right should the left fail.  the second return value is false if neither operand implements
+// Equaler, when both fail the error of the left is returned.
+func equalOverload(a any, b any) (bool, bool, error) {
+	var err error
+	if eq, ok := a.(Equaler); ok {
+		v, leftErr := eq.Equal(b)
+		if leftErr == nil {
+			return v, true, nil
+		}
+		err = leftErr
+	}
+
+	if eq, ok := b.(Equaler); ok {
+		v, rightErr := eq.Equal(a)
+		if rightErr == nil {
+			return v, true, nil
+		}
+		if err == nil {
+			err = rightErr
+		}
+	}
+
+	return false, err != nil, err
+}
+
+// compareOverload dispatches to a Comparer implemented by either operand, preferring the left.  the
+// second return value is false if neither operand implements Comparer.
+func compareOverload(a any, b any) (int, bool, error) {
+	if c, ok := a.(Comparer); ok {
+		v, err := c.Compare(b)
+		return v, true, err
+	}
+
+	if c, ok := b.(Comparer); ok {
+		v, err := c.Compare(a)
+		return -v, true, err
+	}
+
+	return 0, false, nil
+}
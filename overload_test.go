@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type money struct {
+	cents    int64
+	currency string
+}
+
+func (m money) Add(other any) (any, error) {
+	o, ok := other.(money)
+	if !ok || o.currency != m.currency {
+		return nil, fmt.Errorf("cannot add %v to %v", other, m)
+	}
+	return money{cents: m.cents + o.cents, currency: m.currency}, nil
+}
+
+func (m money) Compare(other any) (int, error) {
+	o, ok := other.(money)
+	if !ok || o.currency != m.currency {
+		return 0, fmt.Errorf("cannot compare %v with %v", other, m)
+	}
+	switch {
+	case m.cents < o.cents:
+		return -1, nil
+	case m.cents > o.cents:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m money) Equal(other any) (bool, error) {
+	switch o := other.(type) {
+	case money:
+		return o == m, nil
+	case string:
+		return o == fmt.Sprintf("%d %s", m.cents, m.currency), nil
+	default:
+		return false, fmt.Errorf("cannot compare %v with %v", other, m)
+	}
+}
+
+func (m money) Truthy() bool {
+	return m.cents != 0
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	values := map[string]any{
+		".price":    money{cents: 150, currency: "USD"},
+		".shipping": money{cents: 50, currency: "USD"},
+		".refund":   money{cents: 0, currency: "USD"},
+		".other":    money{cents: 50, currency: "EUR"},
+	}
+	vLookup := func(key string) (any, error) {
+		return values[key], nil
+	}
+
+	for expression, expected := range map[string]any{
+		".price + .shipping":          money{cents: 200, currency: "USD"},
+		".price + .shipping > .price": true,
+		".shipping < .price":          true,
+		".price == '150 USD'":         true,
+		"'150 USD' == .price":         true,
+		".price != .shipping":         true,
+		".refund || 'none'":           "none",
+		".price && 'paid'":            "paid",
+	} {
+		result, err := Evaluate(expression, vLookup, nil)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+
+	_, err := Evaluate(".price + .other", vLookup, nil)
+	assert.Error(t, err)
+
+	_, err = Evaluate(".price > .other", vLookup, nil)
+	assert.Error(t, err)
+
+	_, err = Evaluate(".price - .shipping", vLookup, nil)
+	assert.Error(t, err)
+}
+
+// currencyCode equals any money in its currency
+type currencyCode string
+
+func (c currencyCode) Equal(other any) (bool, error) {
+	m, ok := other.(money)
+	if !ok {
+		return false, fmt.Errorf("cannot compare %v with currency %s", other, string(c))
+	}
+	return m.currency == string(c), nil
+}
+
+func TestEqualerErrors(t *testing.T) {
+	price := money{cents: 150, currency: "USD"}
+
+	// the left operand fails, so the right is used
+	result, err := EqualsOp(price, currencyCode("USD"))
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+	result, err = UnequalsOp(currencyCode("EUR"), price)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+
+	// errors are passed through rather than reported as incompatible types
+	_, err = EqualsOp(price, 1.)
+	assert.EqualError(t, err, "cannot compare 1 with {150 USD}")
+	_, err = UnequalsOp(1., currencyCode("USD"))
+	assert.EqualError(t, err, "cannot compare 1 with currency USD")
+	_, err = EqualsOp([]any{price}, []any{true})
+	assert.EqualError(t, err, "cannot compare true with {150 USD}")
+
+	assert.False(t, DeepEqual(price, 1.))
+	assert.True(t, DeepEqual([]any{price}, []any{currencyCode("USD")}))
+}
+
+func TestStructuralEqualityWithEqualer(t *testing.T) {
+	assert.True(t, DeepEqual([]any{money{cents: 1, currency: "USD"}}, []any{"1 USD"}))
+	assert.False(t, DeepEqual([]any{money{cents: 1, currency: "USD"}}, []any{"2 USD"}))
+	assert.False(t, IsTruthy(money{currency: "USD"}))
+}
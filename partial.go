@@ -0,0 +1,298 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrUnknownVariable is returned by the variable lookup supplied to Program.Partial for variables whose
+// values are not yet known
+var ErrUnknownVariable = errors.New("unknown variable")
+
+// UnknownVariable returns an error wrapping ErrUnknownVariable for the named variable
+func UnknownVariable(name string) error {
+	return fmt.Errorf("%w %s", ErrUnknownVariable, name)
+}
+
+// PartialResult is the result of partially evaluating a program.  when every variable needed was known,
+// Residual is nil and Value holds the result.  otherwise Residual is the program remaining to be
+// evaluated once the unknown variables are available, its Expression method returning its source.
+type PartialResult struct {
+	Residual *Program
+	Value    any
+	// Known lists the variables which were known, but remain referenced by the residual program as their
+	// values cannot be written as literals.  these must also be resolvable when the residual program is
+	// evaluated.
+	Known []string
+}
+
+// Resolved reports whether the program was fully evaluated
+func (r *PartialResult) Resolved() bool {
+	return r.Residual == nil
+}
+
+// Partial evaluates as much of the program as possible using the known variables, for example those of a
+// tenant's configuration, leaving the remainder to be evaluated later.  the lookup returns an error
+// wrapping ErrUnknownVariable (see UnknownVariable) for variables which are not yet known, any other error
+// fails the partial evaluation.
+//
+// known values are substituted into the residual program, and builtin operators are folded as for
+// constant folding, including `&&` and `||` where either operand is known.  function calls are never
+// made, although their arguments are evaluated (see PartialWith).  known values which cannot be written as
+// literals (arrays, mappings, times, host types and strings containing both kinds of quote) are only
+// substituted where they are folded away, otherwise the variable remains in the residual program and is
+// listed by PartialResult.Known.
+func (p *Program) Partial(known VariableLookup) (*PartialResult, error) {
+	return p.PartialWith(&Env{Variables: known})
+}
+
+// PartialWith partially evaluates the program in the same manner as Partial, using the variables of the
+// environment as the known variables.  calls whose arguments are all known are made using the functions
+// of the environment, which return an error wrapping ErrUnknownFunction (see UnknownFunction) for
+// functions which should not be called ahead of evaluation, such as `now`, leaving the call in the
+// residual program.  any other error fails the partial evaluation.
+func (p *Program) PartialWith(env *Env) (*PartialResult, error) {
+	node, err := p.AST()
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return &PartialResult{}, nil
+	}
+
+	if env == nil {
+		env = NewEnv()
+	}
+	pe := &partialEvaluator{
+		ctx:       context.Background(),
+		known:     env.variableLookup(),
+		funcCall:  env.functionCall(),
+		operators: p.operators,
+		resolved:  map[string]bool{},
+	}
+	result, err := pe.evaluate(node)
+	if err != nil {
+		return nil, err
+	}
+	if result.known {
+		return &PartialResult{Value: result.value}, nil
+	}
+
+	residual, err := p.operators.NewProgram(result.node)
+	if err != nil {
+		return nil, err
+	}
+
+	var knownVariables []string
+	Inspect(result.node, func(n Node) bool {
+		if v, ok := n.(*Variable); ok && pe.resolved[v.Path] && !slices.Contains(knownVariables, v.Path) {
+			knownVariables = append(knownVariables, v.Path)
+		}
+		return true
+	})
+	return &PartialResult{Residual: residual, Known: knownVariables}, nil
+}
+
+type partialEvaluator struct {
+	ctx       context.Context
+	known     VariableLookupContext
+	funcCall  FunctionCallContext
+	operators *OperatorRegistry
+	// resolved holds the paths of the variables which were known
+	resolved map[string]bool
+}
+
+// partialValue is the outcome of partially evaluating a node, either a known value, or a residual node
+type partialValue struct {
+	known bool
+	value any
+	node  Node
+}
+
+// evaluate partially evaluates a node
+func (pe *partialEvaluator) evaluate(node Node) (partialValue, error) {
+	switch n := node.(type) {
+	case *Literal:
+		return partialValue{known: true, value: CastToFloat64IfApplicable(n.Value), node: n}, nil
+	case *Variable:
+		if pe.known == nil {
+			return partialValue{node: n}, nil
+		}
+		v, err := pe.known(pe.ctx, n.Path)
+		switch {
+		case errors.Is(err, ErrUnknownVariable):
+			return partialValue{node: n}, nil
+		case err != nil:
+			return partialValue{}, &PositionError{Pos: n.Loc.Start, Err: err}
+		}
+		pe.resolved[n.Path] = true
+		return partialValue{known: true, value: CastToFloat64IfApplicable(v), node: n}, nil
+	case *Call:
+		return pe.evaluateCall(n)
+	case *SubscriptExpr:
+		x, err := pe.evaluate(n.X)
+		if err != nil {
+			return partialValue{}, err
+		}
+		c := *n
+		c.X = x.residual()
+		if x.known {
+			// failures are left to occur when the residual program is evaluated
+			v, err := subscriptImmediate(x.value, n.Path)
+			if err == nil {
+				return partialValue{known: true, value: CastToFloat64IfApplicable(v), node: &c}, nil
+			}
+		}
+		return partialValue{node: &c}, nil
+	case *Binary:
+		return pe.evaluateBinary(n)
+	default:
+		return partialValue{}, fmt.Errorf("unknown node type %T", node)
+	}
+}
+
+// evaluateCall partially evaluates the arguments of a call, making the call if they are all known and the
+// environment supplies the function
+func (pe *partialEvaluator) evaluateCall(n *Call) (partialValue, error) {
+	c := *n
+	c.Args = make([]Node, len(n.Args))
+	values := make([]any, len(n.Args))
+	known := pe.funcCall != nil
+	for i, arg := range n.Args {
+		value := arg
+		switch a := arg.(type) {
+		case *NamedArg:
+			value = a.Value
+		case *Unary:
+			value = a.X
+		}
+
+		v, err := pe.evaluate(value)
+		if err != nil {
+			return partialValue{}, err
+		}
+
+		switch a := arg.(type) {
+		case *NamedArg:
+			named := *a
+			named.Value = v.residual()
+			c.Args[i] = &named
+		case *Unary:
+			spread := *a
+			spread.X = v.residual()
+			c.Args[i] = &spread
+		default:
+			c.Args[i] = v.residual()
+		}
+		known = known && v.known
+		values[i] = v.value
+	}
+	if !known {
+		return partialValue{node: &c}, nil
+	}
+
+	var args []any
+	var namedArgs map[string]any
+	for i, arg := range n.Args {
+		switch a := arg.(type) {
+		case *NamedArg:
+			if namedArgs == nil {
+				namedArgs = map[string]any{}
+			}
+			if _, ok := namedArgs[a.Name]; ok {
+				// malformed calls are left to fail when the residual program is evaluated
+				return partialValue{node: &c}, nil
+			}
+			namedArgs[a.Name] = values[i]
+		case *Unary:
+			items, ok := toArray(values[i])
+			if !ok || len(namedArgs) > 0 {
+				return partialValue{node: &c}, nil
+			}
+			args = append(args, items...)
+		default:
+			if len(namedArgs) > 0 {
+				return partialValue{node: &c}, nil
+			}
+			args = append(args, values[i])
+		}
+	}
+
+	v, err := pe.funcCall(pe.ctx, n.Name, args, namedArgs)
+	switch {
+	case errors.Is(err, ErrUnknownFunction):
+		return partialValue{node: &c}, nil
+	case err != nil:
+		return partialValue{}, &PositionError{Pos: n.Loc.Start, Err: err}
+	}
+	return partialValue{known: true, value: CastToFloat64IfApplicable(v), node: &c}, nil
+}
+
+func (pe *partialEvaluator) evaluateBinary(n *Binary) (partialValue, error) {
+	x, err := pe.evaluate(n.X)
+	if err != nil {
+		return partialValue{}, err
+	}
+	y, err := pe.evaluate(n.Y)
+	if err != nil {
+		return partialValue{}, err
+	}
+
+	c := *n
+	c.X = x.residual()
+	c.Y = y.residual()
+
+	if _, ok := builtinRegistry.Lookup(n.Op); ok {
+		switch {
+		case x.known && y.known:
+			op, _ := pe.operators.Lookup(n.Op)
+			v, err := op.Func(x.value, y.value)
+			if err == nil {
+				return partialValue{known: true, value: CastToFloat64IfApplicable(v), node: &c}, nil
+			}
+		case x.known && (n.Op == OperatorAnd || n.Op == OperatorOr):
+			if IsTruthy(x.value) == (n.Op == OperatorAnd) {
+				return y, nil
+			}
+			return x, nil
+		case y.known && (n.Op == OperatorAnd || n.Op == OperatorOr):
+			// `x && true` and `x || false` are x when x is a boolean
+			if IsTruthy(y.value) == (n.Op == OperatorAnd) && isBoolean(x.node) {
+				return x, nil
+			}
+		}
+	}
+	return partialValue{node: &c}, nil
+}
+
+// isBoolean reports whether a node always evaluates to a boolean, being a comparison, or a logical operator
+// applied to booleans
+func isBoolean(node Node) bool {
+	switch n := node.(type) {
+	case *Literal:
+		_, ok := n.Value.(bool)
+		return ok
+	case *Binary:
+		switch n.Op {
+		case OperatorEquals, OperatorUnequals, OperatorGreater, OperatorGreaterEquals, OperatorLess, OperatorLessEquals:
+			return true
+		case OperatorAnd, OperatorOr:
+			return isBoolean(n.X) && isBoolean(n.Y)
+		}
+	}
+	return false
+}
+
+// residual returns the node representing the value in a residual program, a known value is written as a
+// literal where possible
+func (v partialValue) residual() Node {
+	if s, ok := v.value.(string); ok && !isQuotable(s) {
+		return v.node
+	}
+	if v.known && isLiteralValue(v.value) {
+		return &Literal{Value: v.value, Loc: v.node.Span()}
+	}
+	return v.node
+}
@@ -0,0 +1,193 @@
+package eval
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func knownLookup(values map[string]any) VariableLookup {
+	return func(key string) (any, error) {
+		v, ok := values[key]
+		if !ok {
+			return nil, UnknownVariable(key)
+		}
+		return v, nil
+	}
+}
+
+var tenant = knownLookup(map[string]any{
+	".tenant.tier":     "gold",
+	".tenant.limit":    100,
+	".tenant.enabled":  true,
+	".tenant.disabled": false,
+	".tenant.window":   5 * time.Minute,
+	".tenant.regions":  []any{"eu", "us"},
+	".tenant.config":   map[string]any{"max": 10.},
+	".tenant.start":    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	".tenant.motd":     `it's "quoted"`,
+})
+
+func TestPartialResidual(t *testing.T) {
+	tests := map[string]string{
+		".tenant.tier == 'gold' && .request.size < .tenant.limit": ".request.size < 100",
+		".tenant.tier == 'free' || .request.user == 'admin'":      ".request.user == 'admin'",
+		".request.size * 2 < .tenant.limit * 2":                   ".request.size * 2 < 200",
+		".request.age > .tenant.window":                           ".request.age > 5m",
+		"f(.tenant.limit + 1, ...'regions', n=.tenant.tier)":      "f(101, ...'regions', n='gold')",
+		"len(.tenant.regions) > .request.count":                   "len(.tenant.regions) > .request.count",
+		"(.tenant.config).max > .request.count":                   "10 > .request.count",
+		".request.time > .tenant.start":                           ".request.time > .tenant.start",
+		".tenant.regions && .request.ok":                          ".request.ok",
+		"g(.tenant.regions + .tenant.regions)":                    "g(.tenant.regions + .tenant.regions)",
+		".tenant.tier + 1 > .request.size":                        "'gold' + 1 > .request.size",
+		".request.size":                                           ".request.size",
+		".request.text == .tenant.motd":                           ".request.text == .tenant.motd",
+		"1 + .request.size > 3 && .tenant.enabled":                "1 + .request.size > 3",
+		".request.size > 3 || .tenant.disabled":                   ".request.size > 3",
+		".request.ok && .tenant.enabled":                          ".request.ok && true",
+	}
+
+	for expression, expected := range tests {
+		program, err := Compile(expression)
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+		result, err := program.Partial(tenant)
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+		if !assert.False(t, result.Resolved(), expression) {
+			continue
+		}
+		assert.Equal(t, expected, result.Residual.Expression(), expression)
+	}
+}
+
+func TestPartialResolved(t *testing.T) {
+	tests := map[string]any{
+		".tenant.tier == 'gold' && .tenant.limit > 50": true,
+		".tenant.disabled && .request.size > 0":        false,
+		".tenant.enabled || f()":                       true,
+		".tenant.regions + .tenant.regions":            []any{"eu", "us", "eu", "us"},
+		".tenant.limit * 2":                            float64(200),
+		"'abc'":                                        "abc",
+		"":                                             nil,
+	}
+
+	for expression, expected := range tests {
+		program, err := Compile(expression)
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+		result, err := program.Partial(tenant)
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+		assert.True(t, result.Resolved(), expression)
+		assert.Equal(t, expected, result.Value, expression)
+	}
+}
+
+func TestPartialEvaluateResidual(t *testing.T) {
+	program, err := Compile(".tenant.tier == 'gold' && .request.size < .tenant.limit")
+	assert.NoError(t, err)
+	result, err := program.Partial(tenant)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".request.size"}, result.Residual.Variables())
+
+	for size, expected := range map[float64]bool{50: true, 150: false} {
+		value, err := result.Residual.Evaluate(func(key string) (any, error) {
+			return size, nil
+		}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+
+	// no variables known
+	result, err = program.Partial(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, ".tenant.tier == 'gold' && .request.size < .tenant.limit", result.Residual.Expression())
+}
+
+func TestPartialError(t *testing.T) {
+	program, err := Compile(".a + .b")
+	assert.NoError(t, err)
+
+	lookupErr := errors.New("lookup failed")
+	_, err = program.Partial(func(key string) (any, error) {
+		return nil, lookupErr
+	})
+	assert.ErrorIs(t, err, lookupErr)
+	var posErr *PositionError
+	assert.True(t, errors.As(err, &posErr))
+
+	program, err = Compile("1 2")
+	assert.NoError(t, err)
+	_, err = program.Partial(tenant)
+	assert.Error(t, err)
+}
+
+func TestPartialKnown(t *testing.T) {
+	program, err := Compile("len(.tenant.regions) > 2 && .request.ok || .request.time > .tenant.start + .tenant.window")
+	assert.NoError(t, err)
+	result, err := program.Partial(tenant)
+	assert.NoError(t, err)
+	assert.Equal(t, "len(.tenant.regions) > 2 && .request.ok || .request.time > .tenant.start + 5m", result.Residual.Expression())
+	assert.Equal(t, []string{".tenant.regions", ".tenant.start"}, result.Known)
+
+	result, err = program.Partial(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Known)
+}
+
+func TestPartialWith(t *testing.T) {
+	env := &Env{
+		Variables: tenant,
+		Functions: func(name string, args ...any) (any, error) {
+			switch name {
+			case "len":
+				return len(args[0].([]any)), nil
+			case "count":
+				return len(args), nil
+			case "fail":
+				return nil, errors.New("failed")
+			}
+			return nil, UnknownFunction(name)
+		},
+	}
+
+	tests := map[string]any{
+		"len(.tenant.regions) > 1 && .request.ok":    ".request.ok",
+		"len(.tenant.regions) > 2 && .request.ok":    false,
+		"count(....tenant.regions, 1) == 3":          true,
+		"now() > .request.time":                      "now() > .request.time",
+		"len(.request.items) > len(.tenant.regions)": "len(.request.items) > 2",
+		"upper(.tenant.tier) == .request.tier":       "upper('gold') == .request.tier",
+	}
+	for expression, expected := range tests {
+		program, err := Compile(expression)
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+		result, err := program.PartialWith(env)
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+		if residual, ok := expected.(string); ok && !result.Resolved() {
+			assert.Equal(t, residual, result.Residual.Expression(), expression)
+			continue
+		}
+		assert.Equal(t, expected, result.Value, expression)
+	}
+
+	program, err := Compile("fail(.tenant.tier) || .request.ok")
+	assert.NoError(t, err)
+	_, err = program.PartialWith(env)
+	var posErr *PositionError
+	if assert.True(t, errors.As(err, &posErr)) {
+		assert.Equal(t, 0, posErr.Pos)
+	}
+}
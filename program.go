@@ -0,0 +1,146 @@
+package eval
+
+import (
+	"context"
+)
+
+// Program is a compiled expression, which can be evaluated any number of times without being parsed again
+type Program struct {
+	expression string
+	root       *Token
+	operators  *OperatorRegistry
+
+	// folded is the root with its constant subexpressions folded, which is evaluated in place of root
+	// when set.  root is retained for inspecting, formatting and encoding the program.
+	folded *Token
+}
+
+// Compile parses an expression into a program, recognizing only the builtin operators.  use the Compile
+// method of an OperatorRegistry to compile expressions containing custom operators.
+func Compile(expression string) (*Program, error) {
+	return builtinRegistry.Compile(expression)
+}
+
+// Expression returns the source expression the program was compiled from, or for a program created from a
+// syntax tree using NewProgram, its formatted expression
+func (p *Program) Expression() string {
+	return p.expression
+}
+
+// Evaluate evaluates the program using the supplied variable lookup and function callbacks
+func (p *Program) Evaluate(varLookup VariableLookup, funcCall FunctionCall) (any, error) {
+	return p.EvaluateNamed(varLookup, funcCall.named())
+}
+
+// EvaluateNamed evaluates the program, delivering function calls (including any named arguments) to a
+// NamedFunctionCall
+func (p *Program) EvaluateNamed(varLookup VariableLookup, funcCall NamedFunctionCall) (any, error) {
+	return p.EvaluateWith(&Env{
+		Variables:      varLookup,
+		NamedFunctions: funcCall,
+	})
+}
+
+// EvaluateWith evaluates the program using the callbacks and evaluation flags of the environment.  the
+// program always uses the operators it was compiled with.
+func (p *Program) EvaluateWith(env *Env) (any, error) {
+	return p.EvaluateContext(context.Background(), env)
+}
+
+// EvaluateContext evaluates the program using the environment, passing the context to the callbacks.
+// evaluation stops once the context is done, returning a *PositionError wrapping ctx.Err().
+func (p *Program) EvaluateContext(ctx context.Context, env *Env) (any, error) {
+	if env == nil {
+		env = NewEnv()
+	}
+
+	parentCtx := ctx
+	limits := env.limits()
+	if limits != nil && limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	root := p.root
+	if p.folded != nil {
+		root = p.folded
+	}
+
+	return root.evaluate(&evaluator{
+		ctx:             withLimits(ctx, limits),
+		parentCtx:       parentCtx,
+		limits:          limits,
+		varLookup:       env.variableLookup(),
+		funcCall:        env.functionCall(),
+		operators:       p.operators,
+		strictVariables: env.Enabled(FlagStrictVariables),
+	})
+}
+
+// FunctionUse describes a function call appearing within a program
+type FunctionUse struct {
+	Name string
+	// Args is the number of positional arguments supplied, where a spread argument counts as one
+	Args int
+	// NamedArgs holds the names of any named arguments, in the order supplied
+	NamedArgs []string
+	// Spread is true if any argument is spread, in which case the number of arguments received by the
+	// function is only known once evaluated
+	Spread bool
+	// Pos is the byte offset of the call within the expression
+	Pos int
+}
+
+// Variables returns the variables referenced by the program, including any subscripts (`.a.b[0].c`), in
+// the order in which they first appear.  nothing is evaluated.
+func (p *Program) Variables() []string {
+	var variables []string
+	seen := map[string]struct{}{}
+	p.root.walk(func(t *Token) {
+		if t.Type != TokenTypeVariable {
+			return
+		}
+		path := t.Text + t.Subscript
+		if _, ok := seen[path]; ok {
+			return
+		}
+		seen[path] = struct{}{}
+		variables = append(variables, path)
+	})
+	return variables
+}
+
+// Functions returns each function call made by the program, in the order in which they appear.  nothing is
+// evaluated.
+func (p *Program) Functions() []FunctionUse {
+	var functions []FunctionUse
+	p.root.walk(func(t *Token) {
+		if t.Type != TokenTypeFunction {
+			return
+		}
+		use := FunctionUse{
+			Name: t.Text,
+			Pos:  t.Pos,
+		}
+		for _, arg := range t.Tokens {
+			switch {
+			case arg.Name != "":
+				use.NamedArgs = append(use.NamedArgs, arg.Name)
+			default:
+				use.Args++
+				use.Spread = use.Spread || arg.Spread
+			}
+		}
+		functions = append(functions, use)
+	})
+	return functions
+}
+
+// walk visits the token and all tokens beneath it, depth first in the order in which they appear
+func (t *Token) walk(visit func(t *Token)) {
+	visit(t)
+	for _, sub := range t.Tokens {
+		sub.walk(visit)
+	}
+}
@@ -0,0 +1,244 @@
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ProgramVersion is the version of the JSON program encoding, programs encoded with any other version
+// are rejected
+const ProgramVersion = 1
+
+// ErrProgramVersion is returned when decoding a program encoded with an unsupported version
+var ErrProgramVersion = errors.New("unsupported program encoding version")
+
+// programJSON is the JSON encoding of a program, holding its source expression and syntax tree
+type programJSON struct {
+	Version    int       `json:"version"`
+	Expression string    `json:"expression"`
+	AST        *nodeJSON `json:"ast"`
+
+	// Folded records whether the program's constant subexpressions were folded, programs encoded
+	// without it are folded when decoded
+	Folded *bool `json:"folded,omitempty"`
+}
+
+// nodeJSON is the JSON encoding of a syntax tree node, Node identifies the type of node and the remaining
+// fields are populated according to it
+type nodeJSON struct {
+	Node  string      `json:"node"`
+	Kind  string      `json:"kind,omitempty"`
+	Text  string      `json:"text,omitempty"`
+	Path  string      `json:"path,omitempty"`
+	Name  string      `json:"name,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	X     *nodeJSON   `json:"x,omitempty"`
+	Y     *nodeJSON   `json:"y,omitempty"`
+	Args  []*nodeJSON `json:"args,omitempty"`
+	OpPos int         `json:"opPos,omitempty"`
+	Span  Span        `json:"span"`
+}
+
+// MarshalJSON encodes the program as versioned JSON holding its expression and syntax tree, so that it
+// can be shipped elsewhere and evaluated without being parsed again.  operators are encoded by symbol, the
+// decoding registry must hold any custom operators used.  whether constant subexpressions were folded is
+// also encoded, so a program compiled with folding disabled is not folded when decoded.
+func (p *Program) MarshalJSON() ([]byte, error) {
+	node, err := p.AST()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := encodeNode(node)
+	if err != nil {
+		return nil, err
+	}
+	folded := p.folded != nil
+	return json.Marshal(programJSON{
+		Version:    ProgramVersion,
+		Expression: p.expression,
+		AST:        encoded,
+		Folded:     &folded,
+	})
+}
+
+// UnmarshalJSON decodes a program encoded by MarshalJSON, which may only use the builtin operators.  use
+// the UnmarshalProgram method of an OperatorRegistry to decode programs using custom operators.
+func (p *Program) UnmarshalJSON(data []byte) error {
+	decoded, err := builtinRegistry.UnmarshalProgram(data)
+	if err != nil {
+		return err
+	}
+	*p = *decoded
+	return nil
+}
+
+// UnmarshalProgram decodes a program encoded by Program.MarshalJSON, using the operators held by the
+// registry.  an error wrapping ErrProgramVersion is returned if the encoding version is not supported.
+func (r *OperatorRegistry) UnmarshalProgram(data []byte) (*Program, error) {
+	var encoded programJSON
+	err := json.Unmarshal(data, &encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if encoded.Version != ProgramVersion {
+		return nil, fmt.Errorf("%w %d, expected %d", ErrProgramVersion, encoded.Version, ProgramVersion)
+	}
+
+	var node Node
+	if encoded.AST != nil {
+		node, err = decodeNode(encoded.AST)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	program, err := r.NewProgram(node)
+	if err != nil {
+		return nil, err
+	}
+	program.expression = encoded.Expression
+	if encoded.Folded != nil && !*encoded.Folded {
+		// the program was compiled with folding disabled, or had nothing to fold
+		program.folded = nil
+	}
+	return program, nil
+}
+
+func encodeNode(node Node) (*nodeJSON, error) {
+	switch n := node.(type) {
+	case nil:
+		return nil, nil
+	case *Literal:
+		encoded := &nodeJSON{Node: "literal", Span: n.Loc}
+		switch v := CastToFloat64IfApplicable(n.Value).(type) {
+		case string:
+			encoded.Kind, encoded.Text = "string", v
+		case float64:
+			encoded.Kind, encoded.Text = "number", strconv.FormatFloat(v, 'g', -1, 64)
+		case bool:
+			encoded.Kind, encoded.Text = "boolean", strconv.FormatBool(v)
+		case time.Duration:
+			encoded.Kind, encoded.Text = "duration", v.String()
+		default:
+			return nil, fmt.Errorf("unsupported literal %v of type %T", n.Value, n.Value)
+		}
+		return encoded, nil
+	case *Variable:
+		return &nodeJSON{Node: "variable", Path: n.Path, Span: n.Loc}, nil
+	case *Call:
+		encoded := &nodeJSON{Node: "call", Name: n.Name, Span: n.Loc}
+		for _, arg := range n.Args {
+			a, err := encodeNode(arg)
+			if err != nil {
+				return nil, err
+			}
+			encoded.Args = append(encoded.Args, a)
+		}
+		return encoded, nil
+	case *NamedArg:
+		value, err := encodeNode(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &nodeJSON{Node: "named", Name: n.Name, X: value, Span: n.Loc}, nil
+	case *Unary:
+		x, err := encodeNode(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return &nodeJSON{Node: "unary", Op: n.Op, X: x, Span: n.Loc}, nil
+	case *Binary:
+		x, err := encodeNode(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := encodeNode(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &nodeJSON{Node: "binary", Op: n.Op, X: x, Y: y, OpPos: n.OpPos, Span: n.Loc}, nil
+	case *SubscriptExpr:
+		x, err := encodeNode(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return &nodeJSON{Node: "subscript", Path: n.Path, X: x, Span: n.Loc}, nil
+	default:
+		return nil, fmt.Errorf("unknown node type %T", node)
+	}
+}
+
+func decodeNode(encoded *nodeJSON) (Node, error) {
+	if encoded == nil {
+		return nil, fmt.Errorf("missing node")
+	}
+
+	switch encoded.Node {
+	case "literal":
+		var value any
+		var err error
+		switch encoded.Kind {
+		case "string":
+			value = encoded.Text
+		case "number":
+			value, err = strconv.ParseFloat(encoded.Text, 64)
+		case "boolean":
+			value, err = strconv.ParseBool(encoded.Text)
+		case "duration":
+			value, err = time.ParseDuration(encoded.Text)
+		default:
+			return nil, fmt.Errorf("unknown literal kind %q", encoded.Kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s literal %q: %w", encoded.Kind, encoded.Text, err)
+		}
+		return &Literal{Value: value, Loc: encoded.Span}, nil
+	case "variable":
+		return &Variable{Path: encoded.Path, Loc: encoded.Span}, nil
+	case "call":
+		call := &Call{Name: encoded.Name, Loc: encoded.Span}
+		for _, arg := range encoded.Args {
+			a, err := decodeNode(arg)
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, a)
+		}
+		return call, nil
+	case "named":
+		value, err := decodeNode(encoded.X)
+		if err != nil {
+			return nil, err
+		}
+		return &NamedArg{Name: encoded.Name, Value: value, Loc: encoded.Span}, nil
+	case "unary":
+		x, err := decodeNode(encoded.X)
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Op: encoded.Op, X: x, Loc: encoded.Span}, nil
+	case "binary":
+		x, err := decodeNode(encoded.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeNode(encoded.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Op: encoded.Op, X: x, Y: y, OpPos: encoded.OpPos, Loc: encoded.Span}, nil
+	case "subscript":
+		x, err := decodeNode(encoded.X)
+		if err != nil {
+			return nil, err
+		}
+		return &SubscriptExpr{X: x, Path: encoded.Path, Loc: encoded.Span}, nil
+	default:
+		return nil, fmt.Errorf("unknown node type %q", encoded.Node)
+	}
+}
@@ -0,0 +1,150 @@
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgramJSONRoundTrip(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		".a+.b * 2",
+		"f(.a, ....b, n = 'x').yy > (1 + 2) * 3 || abc == 90m",
+		"2 ** 3 ** 2 - Inf",
+		"('it\"s').xy[0] == \"it's\"",
+		"g() && true",
+	} {
+		program, err := Compile(expression)
+		assert.NoError(t, err)
+
+		data, err := json.Marshal(program)
+		assert.NoError(t, err, expression)
+
+		var decoded Program
+		err = json.Unmarshal(data, &decoded)
+		if !assert.NoError(t, err, expression) {
+			continue
+		}
+		assert.Equal(t, expression, decoded.Expression())
+
+		expected, err := program.AST()
+		assert.NoError(t, err)
+		actual, err := decoded.AST()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual, expression)
+
+		again, err := json.Marshal(&decoded)
+		assert.NoError(t, err)
+		assert.Equal(t, string(data), string(again))
+	}
+}
+
+func TestProgramJSONEvaluate(t *testing.T) {
+	program, err := Compile(".x * 2 + len(.items)")
+	assert.NoError(t, err)
+	data, err := json.Marshal(program)
+	assert.NoError(t, err)
+
+	var decoded Program
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+
+	result, err := decoded.Evaluate(func(key string) (any, error) {
+		switch key {
+		case ".x":
+			return 5, nil
+		default:
+			return []any{1, 2}, nil
+		}
+	}, func(name string, args ...any) (any, error) {
+		return len(args[0].([]any)), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(12), result)
+	assert.Equal(t, []string{".x", ".items"}, decoded.Variables())
+}
+
+func TestProgramJSONVersion(t *testing.T) {
+	var decoded Program
+	err := json.Unmarshal([]byte(`{"version":2,"expression":"1","ast":{"node":"literal","kind":"number","text":"1"}}`), &decoded)
+	assert.True(t, errors.Is(err, ErrProgramVersion))
+	assert.EqualError(t, err, "unsupported program encoding version 2, expected 1")
+
+	err = json.Unmarshal([]byte(`{"expression":"1"}`), &decoded)
+	assert.True(t, errors.Is(err, ErrProgramVersion))
+}
+
+func TestProgramJSONInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"version":1,"ast":{"node":"ternary"}}`,
+		`{"version":1,"ast":{"node":"literal","kind":"time","text":"now"}}`,
+		`{"version":1,"ast":{"node":"literal","kind":"number","text":"abc"}}`,
+		`{"version":1,"ast":{"node":"binary","op":"+","x":{"node":"literal","kind":"number","text":"1"}}}`,
+		`{"version":1,"ast":{"node":"binary","op":"in","x":{"node":"variable","path":".a"},"y":{"node":"variable","path":".b"}}}`,
+		`{"version":1,"ast":{"node":"variable","path":"a"}}`,
+		`{"version":1,"ast":{"node":"variable","path":".a b"}}`,
+		`{"version":1,"ast":{"node":"variable","path":".a+.b"}}`,
+		`{"version":1,"ast":{"node":"variable","path":".a'"}}`,
+		`[]`,
+	} {
+		var decoded Program
+		assert.Error(t, json.Unmarshal([]byte(data), &decoded), data)
+	}
+}
+
+func TestProgramJSONCustomOperators(t *testing.T) {
+	registry := NewOperatorRegistry()
+	assert.NoError(t, registry.Register(Operator{Symbol: "in", Precedence: PrecedenceComparison, Func: func(a, b any) (any, error) {
+		return a == b, nil
+	}}))
+
+	program, err := registry.Compile(".a in .b")
+	assert.NoError(t, err)
+	data, err := json.Marshal(program)
+	assert.NoError(t, err)
+
+	var decoded Program
+	assert.Error(t, json.Unmarshal(data, &decoded))
+
+	custom, err := registry.UnmarshalProgram(data)
+	assert.NoError(t, err)
+	result, err := custom.Evaluate(func(key string) (any, error) {
+		return "x", nil
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, true, result)
+}
+
+func TestProgramJSONMalformed(t *testing.T) {
+	program, err := Compile("1 2")
+	assert.NoError(t, err)
+	_, err = json.Marshal(program)
+	assert.Error(t, err)
+}
+
+func TestProgramJSONFolding(t *testing.T) {
+	env := NewEnv().SetFlag(FlagConstantFolding, false)
+	program, err := CompileWith("60 * 60 * .days", env)
+	assert.NoError(t, err)
+	data, err := json.Marshal(program)
+	assert.NoError(t, err)
+
+	var decoded Program
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Nil(t, decoded.folded)
+
+	program, err = Compile("60 * 60 * .days")
+	assert.NoError(t, err)
+	data, err = json.Marshal(program)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "3600 * .days", foldedString(t, &decoded))
+
+	// encodings without the folded field are folded
+	err = json.Unmarshal([]byte(`{"version":1,"expression":"1 + 2","ast":{"node":"binary","op":"+","opPos":2,`+
+		`"x":{"node":"literal","kind":"number","text":"1"},"y":{"node":"literal","kind":"number","text":"2"}}}`), &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", foldedString(t, &decoded))
+}
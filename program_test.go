@@ -0,0 +1,56 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgramEvaluateRepeatedly(t *testing.T) {
+	program, err := Compile(".x * 2 + 1")
+	assert.NoError(t, err)
+	assert.Equal(t, ".x * 2 + 1", program.Expression())
+
+	for _, x := range []float64{1, 2, 3} {
+		result, err := program.Evaluate(func(key string) (any, error) {
+			return x, nil
+		}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, x*2+1, result)
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	_, err := Compile("(1 + 2")
+	assert.Error(t, err)
+
+	_, err = Compile("1 =! 2")
+	assert.Error(t, err)
+}
+
+func TestProgramVariables(t *testing.T) {
+	program, err := Compile(".a.b[0].c + len(.items, .a.b[0].c) > .limit && f(.x).yz == .z[1]")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{".a.b[0].c", ".items", ".limit", ".x", ".z[1]"}, program.Variables())
+
+	program, err = Compile("1 + 'abc'")
+	assert.NoError(t, err)
+	assert.Empty(t, program.Variables())
+}
+
+func TestProgramFunctions(t *testing.T) {
+	program, err := Compile("now() - parse(.date, 'RFC3339') > 5m && fetch(.url, ....headers, timeout=5, retries=2)")
+	assert.NoError(t, err)
+	assert.Equal(t, []FunctionUse{
+		{Name: "now", Pos: 0},
+		{Name: "parse", Args: 2, Pos: 8},
+		{Name: "fetch", Args: 2, NamedArgs: []string{"timeout", "retries"}, Spread: true, Pos: 40},
+	}, program.Functions())
+
+	program, err = Compile("outer(inner(1), 2)")
+	assert.NoError(t, err)
+	assert.Equal(t, []FunctionUse{
+		{Name: "outer", Args: 2, Pos: 0},
+		{Name: "inner", Args: 1, Pos: 6},
+	}, program.Functions())
+}
@@ -0,0 +1,594 @@
+// Package collections provides functions for reshaping the arrays and mappings supplied to eval
+// expressions, such as sorting, grouping, chunking and picking keys.  arguments are never modified, a new
+// array or mapping being returned instead.
+//
+// functions which select a property of each item (sortBy, groupBy, countBy) accept a subscript path such as
+// `name`, `.meta.name` or `[0]`, which is resolved against each item using eval.Subscript.
+package collections
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/frozengoats/eval"
+	"github.com/frozengoats/eval/stdlib/internal/args"
+	"github.com/frozengoats/eval/stdlib/internal/library"
+)
+
+var functions = library.Functions{
+	"keys": {
+		Func:        keys,
+		Description: "returns the keys of a mapping in sorted order",
+		Params:      []eval.Param{{Name: "m", Type: "mapping of any"}},
+		Returns:     "array of string",
+	},
+	"values": {
+		Func:        values,
+		Description: "returns the values of a mapping, ordered by their keys",
+		Params:      []eval.Param{{Name: "m", Type: "mapping of any"}},
+		Returns:     "array of any",
+	},
+	"entries": {
+		Func:        entries,
+		Description: "returns the entries of a mapping as mappings of `key` and `value`, ordered by key",
+		Params:      []eval.Param{{Name: "m", Type: "mapping of any"}},
+		Returns:     "array of mapping of any",
+	},
+	"first": {
+		Func:        first,
+		Description: "returns the first item of an array, or nil if it is empty",
+		Params:      []eval.Param{{Name: "items", Type: "array of any"}},
+		Returns:     "any",
+	},
+	"last": {
+		Func:        last,
+		Description: "returns the last item of an array, or nil if it is empty",
+		Params:      []eval.Param{{Name: "items", Type: "array of any"}},
+		Returns:     "any",
+	},
+	"reverse": {
+		Func:        reverse,
+		Description: "returns the items of an array in reverse order",
+		Params:      []eval.Param{{Name: "items", Type: "array of any"}},
+		Returns:     "array of any",
+	},
+	"sort": {
+		Func:        sort,
+		Description: "sorts an array using the ordering of the comparison operators",
+		Params:      []eval.Param{{Name: "items", Type: "array of any"}},
+		Returns:     "array of any",
+	},
+	"sortBy": {
+		Func:        sortBy,
+		Description: "sorts an array by a property of each item, keeping the order of equal items",
+		Params: []eval.Param{
+			{Name: "items", Type: "array of any"},
+			{Name: "path", Type: "string", Description: "a subscript resolved against each item, such as `name`, `.meta.name` or `[0]`"},
+		},
+		Returns: "array of any",
+	},
+	"unique": {
+		Func:        unique,
+		Description: "removes duplicate items (by structural equality) from an array, keeping the first occurrence of each",
+		Params:      []eval.Param{{Name: "items", Type: "array of any"}},
+		Returns:     "array of any",
+	},
+	"flatten": {
+		Func:        flatten,
+		Description: "flattens nested arrays by a single level, or by the depth",
+		Params: []eval.Param{
+			{Name: "items", Type: "array of any"},
+			{Name: "depth", Type: "integer", Description: "optional, 1 by default"},
+		},
+		Returns: "array of any",
+	},
+	"chunk": {
+		Func:        chunk,
+		Description: "splits an array into arrays of the size, the last of which may be shorter",
+		Params: []eval.Param{
+			{Name: "items", Type: "array of any"},
+			{Name: "size", Type: "integer"},
+		},
+		Returns: "array of array of any",
+	},
+	"zip": {
+		Func:        zip,
+		Description: "combines arrays into an array of tuples, as long as the shortest array",
+		Params:      []eval.Param{{Name: "arrays", Type: "array of any"}},
+		Variadic:    true,
+		Returns:     "array of array of any",
+	},
+	"groupBy": {
+		Func:        groupBy,
+		Description: "groups the items of an array into a mapping of arrays, keyed by a property of each item",
+		Params: []eval.Param{
+			{Name: "items", Type: "array of any"},
+			{Name: "path", Type: "string", Description: "a subscript resolved against each item, such as `name`, `.meta.name` or `[0]`"},
+		},
+		Returns: "mapping of array of any",
+	},
+	"countBy": {
+		Func:        countBy,
+		Description: "counts the items of an array, keyed by a property of each item",
+		Params: []eval.Param{
+			{Name: "items", Type: "array of any"},
+			{Name: "path", Type: "string", Description: "a subscript resolved against each item, such as `name`, `.meta.name` or `[0]`"},
+		},
+		Returns: "mapping of number",
+	},
+	"indexOf": {
+		Func:        indexOf,
+		Description: "returns the index of the first item structurally equal to the value, or -1",
+		Params: []eval.Param{
+			{Name: "items", Type: "array of any"},
+			{Name: "value", Type: "any"},
+		},
+		Returns: "number",
+	},
+	"merge": {
+		Func:        merge,
+		Description: "shallowly merges mappings, the keys of later mappings taking priority",
+		Params:      []eval.Param{{Name: "mappings", Type: "mapping of any"}},
+		Variadic:    true,
+		Returns:     "mapping of any",
+	},
+	"pick": {
+		Func:        pick,
+		Description: "returns a mapping holding only the keys, supplied as arguments or as an array",
+		Params: []eval.Param{
+			{Name: "m", Type: "mapping of any"},
+			{Name: "keys", Type: "string"},
+		},
+		Variadic: true,
+		Returns:  "mapping of any",
+	},
+	"omit": {
+		Func:        omit,
+		Description: "returns a mapping without the keys, supplied as arguments or as an array",
+		Params: []eval.Param{
+			{Name: "m", Type: "mapping of any"},
+			{Name: "keys", Type: "string"},
+		},
+		Variadic: true,
+		Returns:  "mapping of any",
+	},
+}
+
+// Call executes the named collection function
+func Call(name string, a ...any) (any, error) {
+	return functions.Call(name, a...)
+}
+
+// Names returns the names of all functions in the library, in sorted order
+func Names() []string {
+	return functions.Names()
+}
+
+// Functions returns the descriptions of all functions in the library ordered by name, which can be
+// rendered as a reference using eval.ReferenceMarkdown or eval.ReferenceJSON
+func Functions() []eval.Function {
+	return functions.Reference()
+}
+
+// keys returns the keys of a mapping in sorted order
+func keys(name string, a []any) (any, error) {
+	m, err := mapping(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []any{}
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		result = append(result, k)
+	}
+	return result, nil
+}
+
+// values returns the values of a mapping, ordered by their sorted keys
+func values(name string, a []any) (any, error) {
+	m, err := mapping(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []any{}
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		result = append(result, m[k])
+	}
+	return result, nil
+}
+
+// entries returns the entries of a mapping as `{key, value}` mappings, ordered by key
+func entries(name string, a []any) (any, error) {
+	m, err := mapping(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []any{}
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		result = append(result, map[string]any{
+			"key":   k,
+			"value": m[k],
+		})
+	}
+	return result, nil
+}
+
+// first returns the first item of an array, or nil if it is empty
+func first(name string, a []any) (any, error) {
+	items, err := array(name, a)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// last returns the last item of an array, or nil if it is empty
+func last(name string, a []any) (any, error) {
+	items, err := array(name, a)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[len(items)-1], nil
+}
+
+func reverse(name string, a []any) (any, error) {
+	items, err := array(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	result := slices.Clone(items)
+	slices.Reverse(result)
+	return result, nil
+}
+
+// sort sorts an array using the ordering of the comparison operators
+func sort(name string, a []any) (any, error) {
+	items, err := array(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedBy(name, items, items)
+}
+
+// sortBy sorts an array by a property of each item
+func sortBy(name string, a []any) (any, error) {
+	items, path, err := arrayAndPath(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	sortKeys, err := resolve(name, items, path)
+	if err != nil {
+		return nil, err
+	}
+	return sortedBy(name, items, sortKeys)
+}
+
+// unique returns the items of an array with duplicates (by structural equality) removed, keeping the first
+// occurrence of each
+func unique(name string, a []any) (any, error) {
+	items, err := array(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []any{}
+	for _, item := range items {
+		if !slices.ContainsFunc(result, func(existing any) bool {
+			return eval.DeepEqual(existing, item)
+		}) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// flatten flattens nested arrays by a single level, or by the optional depth
+func flatten(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 2); err != nil {
+		return nil, err
+	}
+	items, err := args.Array(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := 1
+	if len(a) == 2 {
+		depth, err = args.Int(name, a, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return flattenDepth(items, depth), nil
+}
+
+// chunk splits an array into arrays of the given size, the last of which may be shorter
+func chunk(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	items, err := args.Array(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	size, err := args.Int(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+	if size < 1 {
+		return nil, fmt.Errorf("%s size must be at least 1, got %d", name, size)
+	}
+
+	result := []any{}
+	for c := range slices.Chunk(items, size) {
+		result = append(result, slices.Clone(c))
+	}
+	return result, nil
+}
+
+// zip combines arrays into an array of tuples, truncated to the length of the shortest array
+func zip(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, -1); err != nil {
+		return nil, err
+	}
+
+	var arrays [][]any
+	length := -1
+	for i := range a {
+		items, err := args.Array(name, a, i)
+		if err != nil {
+			return nil, err
+		}
+		arrays = append(arrays, items)
+		if length == -1 || len(items) < length {
+			length = len(items)
+		}
+	}
+
+	result := []any{}
+	for i := range length {
+		var tuple []any
+		for _, items := range arrays {
+			tuple = append(tuple, items[i])
+		}
+		result = append(result, tuple)
+	}
+	return result, nil
+}
+
+// groupBy groups the items of an array into a mapping of arrays, keyed by a property of each item
+func groupBy(name string, a []any) (any, error) {
+	items, path, err := arrayAndPath(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	groupKeys, err := resolve(name, items, path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{}
+	for i, item := range items {
+		key, err := keyString(name, groupKeys[i])
+		if err != nil {
+			return nil, err
+		}
+		group, _ := result[key].([]any)
+		result[key] = append(group, item)
+	}
+	return result, nil
+}
+
+// countBy counts the items of an array, keyed by a property of each item
+func countBy(name string, a []any) (any, error) {
+	items, path, err := arrayAndPath(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	countKeys, err := resolve(name, items, path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{}
+	for _, k := range countKeys {
+		key, err := keyString(name, k)
+		if err != nil {
+			return nil, err
+		}
+		count, _ := result[key].(float64)
+		result[key] = count + 1
+	}
+	return result, nil
+}
+
+// indexOf returns the index of the first item structurally equal to the value, or -1
+func indexOf(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	items, err := args.Array(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return float64(slices.IndexFunc(items, func(item any) bool {
+		return eval.DeepEqual(item, a[1])
+	})), nil
+}
+
+// merge shallowly merges mappings, where keys of later mappings take priority
+func merge(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, -1); err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{}
+	for i := range a {
+		m, err := args.Mapping(name, a, i)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(result, m)
+	}
+	return result, nil
+}
+
+// pick returns a mapping containing only the given keys, supplied as arguments or as an array
+func pick(name string, a []any) (any, error) {
+	m, selected, err := mappingAndKeys(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{}
+	for _, k := range selected {
+		if v, ok := m[k]; ok {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+// omit returns a mapping without the given keys, supplied as arguments or as an array
+func omit(name string, a []any) (any, error) {
+	m, omitted, err := mappingAndKeys(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	result := maps.Clone(m)
+	for _, k := range omitted {
+		delete(result, k)
+	}
+	return result, nil
+}
+
+func array(name string, a []any) ([]any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	return args.Array(name, a, 0)
+}
+
+func mapping(name string, a []any) (map[string]any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	return args.Mapping(name, a, 0)
+}
+
+func arrayAndPath(name string, a []any) ([]any, string, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, "", err
+	}
+	items, err := args.Array(name, a, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	path, err := args.String(name, a, 1)
+	if err != nil {
+		return nil, "", err
+	}
+	return items, path, nil
+}
+
+func mappingAndKeys(name string, a []any) (map[string]any, []string, error) {
+	if err := args.Count(name, a, 1, -1); err != nil {
+		return nil, nil, err
+	}
+	m, err := args.Mapping(name, a, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyArgs := a[1:]
+	if len(keyArgs) == 1 {
+		if items, err := args.Array(name, keyArgs, 0); err == nil {
+			keyArgs = items
+		}
+	}
+
+	var keys []string
+	for i := range keyArgs {
+		k, err := args.String(name, keyArgs, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, k)
+	}
+	return m, keys, nil
+}
+
+// resolve resolves the subscript path against every item
+func resolve(name string, items []any, path string) ([]any, error) {
+	var resolved []any
+	for i, item := range items {
+		v, err := eval.Subscript(item, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s unable to resolve %s on item %d: %w", name, path, i, err)
+		}
+		resolved = append(resolved, eval.CastToFloat64IfApplicable(v))
+	}
+	return resolved, nil
+}
+
+// sortedBy returns a stably sorted copy of items, ordered by the corresponding sort keys
+func sortedBy(name string, items []any, sortKeys []any) ([]any, error) {
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	var sortErr error
+	slices.SortStableFunc(indexes, func(i int, j int) int {
+		c, err := eval.Compare(sortKeys[i], sortKeys[j])
+		if err != nil && sortErr == nil {
+			sortErr = fmt.Errorf("%s: %w", name, err)
+		}
+		return c
+	})
+	if sortErr != nil {
+		return nil, sortErr
+	}
+
+	result := make([]any, 0, len(items))
+	for _, i := range indexes {
+		result = append(result, items[i])
+	}
+	return result, nil
+}
+
+func flattenDepth(items []any, depth int) []any {
+	result := []any{}
+	for _, item := range items {
+		nested, ok := item.([]any)
+		if ok && depth > 0 {
+			result = append(result, flattenDepth(nested, depth-1)...)
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// keyString converts a grouping key to a mapping key
+func keyString(name string, key any) (string, error) {
+	switch t := key.(type) {
+	case string:
+		return t, nil
+	case float64, bool:
+		return fmt.Sprint(t), nil
+	default:
+		return "", fmt.Errorf("%s key must be a string, number or boolean, got %T", name, key)
+	}
+}
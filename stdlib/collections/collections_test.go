@@ -0,0 +1,90 @@
+package collections
+
+import (
+	"testing"
+
+	"github.com/frozengoats/eval"
+	"github.com/stretchr/testify/assert"
+)
+
+var data = map[string]any{
+	".m": map[string]any{"b": 2., "a": 1., "c": 3.},
+	".n": map[string]any{"c": 4., "d": 5.},
+	".people": []any{
+		map[string]any{"name": "bob", "age": 30., "team": "red"},
+		map[string]any{"name": "al", "age": 25., "team": "blue"},
+		map[string]any{"name": "cy", "age": 30., "team": "red"},
+	},
+	".nums":   []any{3., 1., 2., 1.},
+	".nested": []any{1., []any{2., []any{3.}}},
+}
+
+func vLookup(key string) (any, error) {
+	return data[key], nil
+}
+
+func TestCollectionFunctions(t *testing.T) {
+	for expression, expected := range map[string]any{
+		"keys(.m)":                              []any{"a", "b", "c"},
+		"values(.m)":                            []any{1., 2., 3.},
+		"entries(.n)[1].key":                    "d",
+		"first(.nums) + last(.nums)":            4.,
+		"reverse(.nums)":                        []any{1., 2., 1., 3.},
+		"sort(.nums)":                           []any{1., 1., 2., 3.},
+		"sortBy(.people, 'age')[0].name":        "al",
+		"sortBy(.people, '.age')[2].name":       "cy",
+		"unique(.nums)":                         []any{3., 1., 2.},
+		"flatten(.nested)":                      []any{1., 2., []any{3.}},
+		"flatten(.nested, 5)":                   []any{1., 2., 3.},
+		"chunk(.nums, 3)":                       []any{[]any{3., 1., 2.}, []any{1.}},
+		"zip(.nums, keys(.m))":                  []any{[]any{3., "a"}, []any{1., "b"}, []any{2., "c"}},
+		"groupBy(.people, 'team').blue[0].name": "al",
+		"countBy(.people, 'age')":               map[string]any{"30": 2., "25": 1.},
+		"indexOf(.nums, 2)":                     2.,
+		"indexOf(.nums, 7)":                     -1.,
+		"merge(.m, .n)":                         map[string]any{"a": 1., "b": 2., "c": 4., "d": 5.},
+		"pick(.m, 'a', 'c', 'z')":               map[string]any{"a": 1., "c": 3.},
+		"omit(.m, keys(.n))":                    map[string]any{"a": 1., "b": 2.},
+	} {
+		result, err := eval.Evaluate(expression, vLookup, Call)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestCollectionFunctionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"keys(.nums)",
+		"sort(.people)",
+		"sortBy(.nums, 'age')",
+		"chunk(.nums, 0)",
+		"groupBy(.people, 'missing')",
+		"merge(.m, .nums)",
+		"pick(.m, 1)",
+	} {
+		_, err := eval.Evaluate(expression, vLookup, Call)
+		assert.Error(t, err, expression)
+	}
+
+	result, err := Call("first", []any{})
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+
+	_, err = Call("nope")
+	assert.ErrorIs(t, err, eval.ErrUnknownFunction)
+}
+
+func TestFunctionReference(t *testing.T) {
+	var names []string
+	for _, f := range Functions() {
+		names = append(names, f.Name)
+		assert.NotEmpty(t, f.Description, f.Name)
+		assert.NotEmpty(t, f.Returns, f.Name)
+		for _, example := range f.Examples {
+			result, err := eval.Evaluate(example, nil, Call)
+			assert.NoError(t, err, example)
+			assert.Equal(t, true, result, example)
+		}
+	}
+	assert.Equal(t, Names(), names)
+}
@@ -0,0 +1,338 @@
+// Package encoding provides functions for decoding and encoding JSON, YAML, base64, hex and URLs within eval
+// expressions, along with sha256, md5 and crc32 hashes.
+//
+// decoded documents are returned as the []any, map[string]any and float64 values understood by the
+// evaluator, so that they can be subscripted directly:
+//
+//	result, err := eval.Evaluate("jsonParse(.body).items[0].id == 5", vLookup, encoding.Call)
+package encoding
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/url"
+
+	"github.com/frozengoats/eval"
+	"github.com/frozengoats/eval/stdlib/internal/args"
+	"github.com/frozengoats/eval/stdlib/internal/library"
+	"gopkg.in/yaml.v3"
+)
+
+var functions = library.Functions{
+	"jsonParse": {
+		Func:        jsonParse,
+		Description: "decodes a JSON document into arrays, mappings, strings, float64 numbers and booleans",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "any",
+		Examples:    []string{"jsonParse('{\"items\": [1, 2]}').items[1] == 2"},
+	},
+	"jsonStringify": {
+		Func:        jsonStringify,
+		Description: "encodes a value as compact JSON, or as indented JSON if an indent is supplied",
+		Params: []eval.Param{
+			{Name: "value", Type: "any"},
+			{Name: "indent", Type: "string", Description: "optional, the indent of each level"},
+		},
+		Returns: "string",
+	},
+	"yamlParse": {
+		Func:        yamlParse,
+		Description: "decodes a YAML document into arrays, mappings, strings, float64 numbers and booleans, converting mapping keys to strings",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "any",
+	},
+	"base64Encode": {
+		Func:        base64Encode,
+		Description: "encodes a string as standard padded base64",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+		Examples:    []string{"base64Encode('hi') == 'aGk='"},
+	},
+	"base64Decode": {
+		Func:        base64Decode,
+		Description: "decodes standard base64, with or without padding",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"hexEncode": {
+		Func:        hexEncode,
+		Description: "encodes a string as lower case hex",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"hexDecode": {
+		Func:        hexDecode,
+		Description: "decodes a hex encoded string",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"urlParse": {
+		Func:        urlParse,
+		Description: "breaks a URL into a mapping of scheme, user, host, hostname, port, path, query (the first value of each parameter), rawQuery and fragment",
+		Params:      []eval.Param{{Name: "url", Type: "string"}},
+		Returns:     "mapping of any",
+		Examples:    []string{"urlParse('https://example.com:8080/a?id=c').query.id == 'c'"},
+	},
+	"queryEscape": {
+		Func:        queryEscape,
+		Description: "escapes a string for use in a URL query",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"queryUnescape": {
+		Func:        queryUnescape,
+		Description: "reverses queryEscape",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"sha256": {
+		Func:        sha256Sum,
+		Description: "returns the hex encoded SHA-256 digest of a string",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"md5": {
+		Func:        md5Sum,
+		Description: "returns the hex encoded MD5 digest of a string",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"crc32": {
+		Func:        crc32Sum,
+		Description: "returns the IEEE CRC-32 checksum of a string as a number, suitable for bucketing",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "number",
+	},
+}
+
+// Call executes the named encoding function
+func Call(name string, a ...any) (any, error) {
+	return functions.Call(name, a...)
+}
+
+// Names returns the names of all functions in the library, in sorted order
+func Names() []string {
+	return functions.Names()
+}
+
+// Functions returns the descriptions of all functions in the library ordered by name, which can be
+// rendered as a reference using eval.ReferenceMarkdown or eval.ReferenceJSON
+func Functions() []eval.Function {
+	return functions.Reference()
+}
+
+func jsonParse(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	err = json.Unmarshal([]byte(s), &v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+// jsonStringify encodes a value as compact JSON, or as indented JSON if an indent string is supplied
+func jsonStringify(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 2); err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	var err error
+	if len(a) == 2 {
+		var indent string
+		indent, err = args.String(name, a, 1)
+		if err != nil {
+			return nil, err
+		}
+		b, err = json.MarshalIndent(a[0], "", indent)
+	} else {
+		b, err = json.Marshal(a[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return string(b), nil
+}
+
+// yamlParse decodes a YAML document, mapping keys are converted to strings and numbers to float64
+func yamlParse(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any
+	err = yaml.Unmarshal([]byte(s), &v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return normalize(v), nil
+}
+
+func base64Encode(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.EncodeToString([]byte(s)), nil
+}
+
+// base64Decode decodes standard base64, with or without padding
+func base64Decode(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		b, err = base64.RawStdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return string(b), nil
+}
+
+func hexEncode(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString([]byte(s)), nil
+}
+
+func hexDecode(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return string(b), nil
+}
+
+// urlParse breaks a URL into a mapping of its components.  query holds the first value of each query
+// parameter, while rawQuery holds the unparsed query string.
+func urlParse(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	query := map[string]any{}
+	for k, v := range u.Query() {
+		query[k] = v[0]
+	}
+
+	return map[string]any{
+		"scheme":   u.Scheme,
+		"user":     u.User.Username(),
+		"host":     u.Host,
+		"hostname": u.Hostname(),
+		"port":     u.Port(),
+		"path":     u.Path,
+		"query":    query,
+		"rawQuery": u.RawQuery,
+		"fragment": u.Fragment,
+	}, nil
+}
+
+func queryEscape(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return url.QueryEscape(s), nil
+}
+
+func queryUnescape(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	unescaped, err := url.QueryUnescape(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return unescaped, nil
+}
+
+// sha256Sum returns the hex encoded SHA-256 digest of a string
+func sha256Sum(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// md5Sum returns the hex encoded MD5 digest of a string
+func md5Sum(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// crc32Sum returns the IEEE CRC-32 checksum of a string as a number, suitable for bucketing
+func crc32Sum(name string, a []any) (any, error) {
+	s, err := stringArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return float64(crc32.ChecksumIEEE([]byte(s))), nil
+}
+
+func stringArg(name string, a []any) (string, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return "", err
+	}
+	return args.String(name, a, 0)
+}
+
+// normalize converts decoded YAML values into the types understood by the evaluator
+func normalize(value any) any {
+	switch t := value.(type) {
+	case map[string]any:
+		for k, v := range t {
+			t[k] = normalize(v)
+		}
+		return t
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = normalize(v)
+		}
+		return m
+	case []any:
+		for i, v := range t {
+			t[i] = normalize(v)
+		}
+		return t
+	default:
+		return eval.CastToFloat64IfApplicable(value)
+	}
+}
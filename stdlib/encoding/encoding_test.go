@@ -0,0 +1,82 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/frozengoats/eval"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodingFunctions(t *testing.T) {
+	vLookup := func(key string) (any, error) {
+		switch key {
+		case ".body":
+			return `{"items": [{"id": 5, "tags": ["a", "b"]}], "ok": true}`, nil
+		case ".manifest":
+			return "name: web\nreplicas: 3\nports:\n  - 80\n  - 443\n", nil
+		default:
+			return nil, nil
+		}
+	}
+
+	for expression, expected := range map[string]any{
+		"jsonParse(.body).items[0].id == 5":                                true,
+		"jsonParse(.body).items[0].tags":                                   []any{"a", "b"},
+		"jsonParse('[1, \"x\", null]')":                                    []any{1., "x", nil},
+		"jsonStringify(jsonParse(.body).items[0])":                         `{"id":5,"tags":["a","b"]}`,
+		"jsonStringify(jsonParse('[1]'), '  ')":                            "[\n  1\n]",
+		"yamlParse(.manifest).replicas + 1":                                4.,
+		"yamlParse(.manifest).ports[1]":                                    443.,
+		"jsonStringify(yamlParse('1: one'))":                               `{"1":"one"}`,
+		"base64Encode('hello')":                                            "aGVsbG8=",
+		"base64Decode('aGVsbG8=') == base64Decode('aGVsbG8')":              true,
+		"hexEncode('hi')":                                                  "6869",
+		"hexDecode('6869')":                                                "hi",
+		"urlParse('https://u@example.com:8443/a/b?x=1&y=2#top').port":      "8443",
+		"urlParse('https://u@example.com:8443/a/b?x=1&yy=2#top').query.yy": "2",
+		"urlParse('https://example.com/a/b').path":                         "/a/b",
+		"queryEscape('a b&c')":                                             "a+b%26c",
+		"queryUnescape('a+b%26c')":                                         "a b&c",
+		"sha256('abc')":                                                    "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"md5('abc')":                                                       "900150983cd24fb0d6963f7d28e17f72",
+		"crc32('abc')":                                                     891568578.,
+		"sha256(hexDecode(hexEncode('abc'))) == sha256('abc')":             true,
+	} {
+		result, err := eval.Evaluate(expression, vLookup, Call)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestEncodingFunctionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"jsonParse('{')",
+		"yamlParse('a: [')",
+		"base64Decode('!!')",
+		"hexDecode('xyz')",
+		"queryUnescape('%zz')",
+		"sha256(1)",
+		"md5('a', 'b')",
+	} {
+		_, err := eval.Evaluate(expression, nil, Call)
+		assert.Error(t, err, expression)
+	}
+
+	_, err := Call("nope")
+	assert.ErrorIs(t, err, eval.ErrUnknownFunction)
+}
+
+func TestFunctionReference(t *testing.T) {
+	var names []string
+	for _, f := range Functions() {
+		names = append(names, f.Name)
+		assert.NotEmpty(t, f.Description, f.Name)
+		assert.NotEmpty(t, f.Returns, f.Name)
+		for _, example := range f.Examples {
+			result, err := eval.Evaluate(example, nil, Call)
+			assert.NoError(t, err, example)
+			assert.Equal(t, true, result, example)
+		}
+	}
+	assert.Equal(t, Names(), names)
+}
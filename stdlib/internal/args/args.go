@@ -0,0 +1,139 @@
+// Package args provides argument validation shared by the standard function libraries
+package args
+
+import (
+	"fmt"
+
+	"github.com/frozengoats/eval"
+)
+
+// Count validates the number of arguments supplied to a function, max may be -1 for no upper bound
+func Count(name string, args []any, min int, max int) error {
+	switch {
+	case min == max && len(args) != min:
+		return fmt.Errorf("%s expects %d argument(s), got %d", name, min, len(args))
+	case len(args) < min:
+		return fmt.Errorf("%s expects at least %d argument(s), got %d", name, min, len(args))
+	case max >= 0 && len(args) > max:
+		return fmt.Errorf("%s expects at most %d argument(s), got %d", name, max, len(args))
+	default:
+		return nil
+	}
+}
+
+// String returns argument i as a string
+func String(name string, args []any, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", typeError(name, args, i, "string")
+	}
+	return s, nil
+}
+
+// Number returns argument i as a float64, accepting any numeric kind
+func Number(name string, args []any, i int) (float64, error) {
+	n, ok := eval.CastToFloat64IfApplicable(args[i]).(float64)
+	if !ok {
+		return 0, typeError(name, args, i, "number")
+	}
+	return n, nil
+}
+
+// Int returns argument i as an int, the argument must be a whole number
+func Int(name string, args []any, i int) (int, error) {
+	n, err := Number(name, args, i)
+	if err != nil {
+		return 0, err
+	}
+	if n != float64(int(n)) {
+		return 0, fmt.Errorf("%s argument %d must be a whole number, got %v", name, i+1, n)
+	}
+	return int(n), nil
+}
+
+// Bool returns argument i as a bool
+func Bool(name string, args []any, i int) (bool, error) {
+	b, ok := args[i].(bool)
+	if !ok {
+		return false, typeError(name, args, i, "boolean")
+	}
+	return b, nil
+}
+
+// Array returns argument i as a []any, accepting the typed slices understood by the evaluator
+func Array(name string, args []any, i int) ([]any, error) {
+	switch t := args[i].(type) {
+	case []any:
+		return t, nil
+	case []string:
+		items := make([]any, 0, len(t))
+		for _, v := range t {
+			items = append(items, v)
+		}
+		return items, nil
+	case []float64:
+		items := make([]any, 0, len(t))
+		for _, v := range t {
+			items = append(items, v)
+		}
+		return items, nil
+	case []int:
+		items := make([]any, 0, len(t))
+		for _, v := range t {
+			items = append(items, float64(v))
+		}
+		return items, nil
+	case []int64:
+		items := make([]any, 0, len(t))
+		for _, v := range t {
+			items = append(items, float64(v))
+		}
+		return items, nil
+	case []byte:
+		items := make([]any, 0, len(t))
+		for _, v := range t {
+			items = append(items, float64(v))
+		}
+		return items, nil
+	default:
+		return nil, typeError(name, args, i, "array")
+	}
+}
+
+// Mapping returns argument i as a map[string]any
+func Mapping(name string, args []any, i int) (map[string]any, error) {
+	m, ok := args[i].(map[string]any)
+	if !ok {
+		return nil, typeError(name, args, i, "mapping")
+	}
+	return m, nil
+}
+
+func typeError(name string, args []any, i int, expected string) error {
+	return fmt.Errorf("%s argument %d must be a %s, got %T", name, i+1, expected, args[i])
+}
+
+// Numbers returns the numeric values of a function's arguments, which are either all numbers, or a single
+// array of numbers
+func Numbers(name string, args []any) ([]float64, error) {
+	values := args
+	if len(args) == 1 {
+		if items, err := Array(name, args, 0); err == nil {
+			values = items
+		}
+	}
+
+	numbers := make([]float64, 0, len(values))
+	for i, v := range values {
+		n, ok := eval.CastToFloat64IfApplicable(v).(float64)
+		if !ok {
+			return nil, fmt.Errorf("%s item %d must be a number, got %T", name, i+1, v)
+		}
+		numbers = append(numbers, n)
+	}
+
+	if len(numbers) == 0 {
+		return nil, fmt.Errorf("%s requires at least one number", name)
+	}
+	return numbers, nil
+}
@@ -0,0 +1,79 @@
+// Package library provides the function dispatch and documentation shared by the standard function
+// libraries
+package library
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/frozengoats/eval"
+)
+
+// Func implements a library function, receiving the name it was called by for use in error messages
+type Func func(name string, args []any) (any, error)
+
+// FuncContext implements a library function which needs the context of the evaluation, such as to read its
+// limits using eval.LimitsFromContext
+type FuncContext func(ctx context.Context, name string, args []any) (any, error)
+
+// Function is a library function along with the documentation presented to expression authors, see
+// eval.Function
+type Function struct {
+	// Func implements the function, unless FuncContext is set
+	Func        Func
+	FuncContext FuncContext
+	Description string
+	Params      []eval.Param
+	Variadic    bool
+	Returns     string
+	Examples    []string
+}
+
+// Functions maps the names of a library's functions to their implementations
+type Functions map[string]Function
+
+// Call executes the named function, returning an error wrapping eval.ErrUnknownFunction for names which
+// are not in the library, so that it can be chained with other callbacks using eval.ChainFunctions
+func (f Functions) Call(name string, args ...any) (any, error) {
+	return f.CallContext(context.Background(), name, args, nil)
+}
+
+// CallContext executes the named function as Call does, it is compatible with eval.FunctionCallContext and
+// passes the context of the evaluation to functions needing it.  named arguments are rejected.
+func (f Functions) CallContext(ctx context.Context, name string, args []any, namedArgs map[string]any) (any, error) {
+	fn, ok := f[name]
+	if !ok {
+		return nil, eval.UnknownFunction(name)
+	}
+	if len(namedArgs) > 0 {
+		return nil, fmt.Errorf("function %s does not accept named arguments", name)
+	}
+	if fn.FuncContext != nil {
+		return fn.FuncContext(ctx, name, args)
+	}
+	return fn.Func(name, args)
+}
+
+// Names returns the names of all functions in the library, in sorted order
+func (f Functions) Names() []string {
+	return slices.Sorted(maps.Keys(f))
+}
+
+// Reference returns the descriptions of all functions in the library, ordered by name
+func (f Functions) Reference() []eval.Function {
+	var functions []eval.Function
+	for _, name := range f.Names() {
+		fn := f[name]
+		functions = append(functions, eval.Function{
+			Name:        name,
+			Description: fn.Description,
+			Params:      slices.Clone(fn.Params),
+			Variadic:    fn.Variadic,
+			Returns:     fn.Returns,
+			Examples:    slices.Clone(fn.Examples),
+		})
+	}
+	return functions
+}
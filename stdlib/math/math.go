@@ -0,0 +1,291 @@
+// Package math provides rounding, logarithm and statistics functions for eval expressions.  numbers of any
+// kind are accepted, and results are float64 like every number within an expression.
+//
+// aggregate functions (min, max, sum, avg, median, stddev) accept either a single array of numbers, or
+// any number of numeric arguments.
+package math
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/frozengoats/eval"
+	"github.com/frozengoats/eval/stdlib/internal/args"
+	"github.com/frozengoats/eval/stdlib/internal/library"
+)
+
+var functions = library.Functions{
+	"abs":   unary(math.Abs, "returns the absolute value of a number"),
+	"floor": unary(math.Floor, "rounds a number down to the nearest whole number"),
+	"ceil":  unary(math.Ceil, "rounds a number up to the nearest whole number"),
+	"round": {
+		Func:        round,
+		Description: "rounds a number half away from zero, to the number of decimal digits (negative digits rounding to tens, hundreds and so on)",
+		Params: []eval.Param{
+			{Name: "x", Type: "number"},
+			{Name: "digits", Type: "integer", Description: "optional, 0 by default"},
+		},
+		Returns:  "number",
+		Examples: []string{"round(3.14159, 2) == 3.14", "round(1250, 0 - 2) == 1300"},
+	},
+	"sqrt": {
+		Func:        sqrt,
+		Description: "returns the square root of a non-negative number",
+		Params:      []eval.Param{{Name: "x", Type: "number"}},
+		Returns:     "number",
+	},
+	"log": {
+		Func:        log,
+		Description: "returns the natural logarithm of a positive number, or its logarithm in the base",
+		Params: []eval.Param{
+			{Name: "x", Type: "number"},
+			{Name: "base", Type: "number", Description: "optional, positive and not 1"},
+		},
+		Returns:  "number",
+		Examples: []string{"log(8, 2) == 3"},
+	},
+	"clamp": {
+		Func:        clamp,
+		Description: "limits a number to the range lower to upper inclusive",
+		Params: []eval.Param{
+			{Name: "x", Type: "number"},
+			{Name: "lower", Type: "number"},
+			{Name: "upper", Type: "number"},
+		},
+		Returns:  "number",
+		Examples: []string{"clamp(15, 0, 10) == 10"},
+	},
+	"min":    aggregate(minimum, "returns the smallest number"),
+	"max":    aggregate(maximum, "returns the largest number"),
+	"sum":    aggregate(sum, "returns the total of the numbers"),
+	"avg":    aggregate(avg, "returns the mean of the numbers"),
+	"median": aggregate(median, "returns the median of the numbers, the mean of the middle two for an even count"),
+	"stddev": aggregate(stddev, "returns the population standard deviation of the numbers"),
+	"percentile": {
+		Func:        percentile,
+		Description: "returns the p-th percentile of an array of numbers, interpolating linearly between the closest ranks",
+		Params: []eval.Param{
+			{Name: "numbers", Type: "array of number"},
+			{Name: "p", Type: "number", Description: "between 0 and 100"},
+		},
+		Returns: "number",
+	},
+}
+
+// Call executes the named math function
+func Call(name string, a ...any) (any, error) {
+	return functions.Call(name, a...)
+}
+
+// Names returns the names of all functions in the library, in sorted order
+func Names() []string {
+	return functions.Names()
+}
+
+// Functions returns the descriptions of all functions in the library ordered by name, which can be
+// rendered as a reference using eval.ReferenceMarkdown or eval.ReferenceJSON
+func Functions() []eval.Function {
+	return functions.Reference()
+}
+
+// unary adapts a function of a single number
+func unary(f func(float64) float64, description string) library.Function {
+	return library.Function{
+		Func: func(name string, a []any) (any, error) {
+			if err := args.Count(name, a, 1, 1); err != nil {
+				return nil, err
+			}
+			x, err := args.Number(name, a, 0)
+			if err != nil {
+				return nil, err
+			}
+			return f(x), nil
+		},
+		Description: description,
+		Params:      []eval.Param{{Name: "x", Type: "number"}},
+		Returns:     "number",
+	}
+}
+
+// aggregate adapts a function of a list of numbers, supplied either as a single array or as arguments
+func aggregate(f func([]float64) float64, description string) library.Function {
+	return library.Function{
+		Func: func(name string, a []any) (any, error) {
+			numbers, err := args.Numbers(name, a)
+			if err != nil {
+				return nil, err
+			}
+			return f(numbers), nil
+		},
+		Description: description + ", supplied either as a single array or as any number of arguments",
+		Params:      []eval.Param{{Name: "numbers", Type: "number"}},
+		Variadic:    true,
+		Returns:     "number",
+	}
+}
+
+// round rounds half away from zero, to the optional number of decimal digits
+func round(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 2); err != nil {
+		return nil, err
+	}
+	x, err := args.Number(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	digits := 0
+	if len(a) == 2 {
+		digits, err = args.Int(name, a, 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// digits beyond the range of a float64 are clamped, rounding a number to more decimal digits than it
+	// can hold leaves it unchanged, and rounding it to more integer digits than it has gives zero
+	if digits < 0 {
+		scale := math.Pow(10, float64(-digits))
+		if math.IsInf(scale, 1) {
+			return math.Copysign(0, x), nil
+		}
+		return math.Round(x/scale) * scale, nil
+	}
+
+	scale := math.Pow(10, float64(digits))
+	if math.IsInf(x*scale, 0) {
+		return x, nil
+	}
+	return math.Round(x*scale) / scale, nil
+}
+
+func sqrt(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	x, err := args.Number(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	if x < 0 {
+		return nil, fmt.Errorf("%s of negative number %v", name, x)
+	}
+	return math.Sqrt(x), nil
+}
+
+// log returns the natural logarithm, or the logarithm in the optional base
+func log(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 2); err != nil {
+		return nil, err
+	}
+	x, err := args.Number(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	if x <= 0 {
+		return nil, fmt.Errorf("%s of non-positive number %v", name, x)
+	}
+
+	if len(a) == 1 {
+		return math.Log(x), nil
+	}
+
+	base, err := args.Number(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+	if base <= 0 || base == 1 {
+		return nil, fmt.Errorf("%s base must be positive and not 1, got %v", name, base)
+	}
+	return math.Log(x) / math.Log(base), nil
+}
+
+// clamp limits a number to the range [lower, upper]
+func clamp(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 3, 3); err != nil {
+		return nil, err
+	}
+	var n [3]float64
+	for i := range n {
+		v, err := args.Number(name, a, i)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = v
+	}
+	if n[1] > n[2] {
+		return nil, fmt.Errorf("%s lower bound %v is greater than upper bound %v", name, n[1], n[2])
+	}
+	return min(max(n[0], n[1]), n[2]), nil
+}
+
+// percentile returns the p-th percentile (0-100) of an array of numbers, interpolating linearly between
+// the closest ranks
+func percentile(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	items, err := args.Array(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := args.Numbers(name, items)
+	if err != nil {
+		return nil, err
+	}
+	p, err := args.Number(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(p) || p < 0 || p > 100 {
+		return nil, fmt.Errorf("%s must be between 0 and 100, got %v", name, p)
+	}
+
+	sorted := slices.Clone(numbers)
+	slices.Sort(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower)), nil
+}
+
+func minimum(numbers []float64) float64 {
+	return slices.Min(numbers)
+}
+
+func maximum(numbers []float64) float64 {
+	return slices.Max(numbers)
+}
+
+func sum(numbers []float64) float64 {
+	var total float64
+	for _, n := range numbers {
+		total += n
+	}
+	return total
+}
+
+func avg(numbers []float64) float64 {
+	return sum(numbers) / float64(len(numbers))
+}
+
+func median(numbers []float64) float64 {
+	sorted := slices.Clone(numbers)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// stddev returns the population standard deviation
+func stddev(numbers []float64) float64 {
+	mean := avg(numbers)
+	var variance float64
+	for _, n := range numbers {
+		variance += (n - mean) * (n - mean)
+	}
+	return math.Sqrt(variance / float64(len(numbers)))
+}
@@ -0,0 +1,101 @@
+package math
+
+import (
+	"testing"
+
+	"github.com/frozengoats/eval"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMathFunctions(t *testing.T) {
+	vLookup := func(key string) (any, error) {
+		return []any{2., 4., 4., 4., 5., 5., 7., 9.}, nil
+	}
+
+	for expression, expected := range map[string]any{
+		"abs(0 - 3)":               3.,
+		"floor(2.7) + ceil(2.1)":   5.,
+		"round(2.5)":               3.,
+		"round(3.14159, 2)":        3.14,
+		"round(1250, 0 - 2)":       1300.,
+		"round(1.5, 400)":          1.5,
+		"round(1250, 0 - 400)":     0.,
+		"round(2 ** 1000, 10)":     1.0715086071862673e+301,
+		"sqrt(16)":                 4.,
+		"log(8, 2)":                3.,
+		"clamp(15, 0, 10)":         10.,
+		"clamp(0 - 5, 0, 10)":      0.,
+		"min(3, 1, 2)":             1.,
+		"max(.values)":             9.,
+		"sum(.values)":             40.,
+		"avg(.values)":             5.,
+		"median(.values)":          4.5,
+		"median(3, 1, 2)":          2.,
+		"stddev(.values)":          2.,
+		"percentile(.values, 0)":   2.,
+		"percentile(.values, 50)":  4.5,
+		"percentile(.values, 100)": 9.,
+	} {
+		result, err := eval.Evaluate(expression, vLookup, Call)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+
+	// byte slices are arrays of numbers, as they are to the evaluator
+	result, err := Call("sum", []byte{1, 2, 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 6., result)
+}
+
+func TestMathFunctionErrors(t *testing.T) {
+	vLookup := func(key string) (any, error) {
+		return []any{}, nil
+	}
+
+	for _, expression := range []string{
+		"abs('a')",
+		"sum(.empty)",
+		"avg()",
+		"max(1, 'a')",
+		"sqrt(0 - 1)",
+		"log(0)",
+		"log(8, 1)",
+		"clamp(1, 10, 0)",
+		"percentile(.empty, 50)",
+		"percentile(split, 150)",
+		"round(1.5, 0.5)",
+	} {
+		_, err := eval.Evaluate(expression, vLookup, Call)
+		assert.Error(t, err, expression)
+	}
+
+	vLookup = func(key string) (any, error) {
+		return []any{1., 2., 3.}, nil
+	}
+	for _, expression := range []string{
+		"percentile(.values, 2 ** 2000 - 2 ** 2000)",
+		"percentile(.values, 2 ** 2000)",
+		"percentile(.values, 0 - 2 ** 2000)",
+	} {
+		_, err := eval.Evaluate(expression, vLookup, Call)
+		assert.Error(t, err, expression)
+	}
+
+	_, err := Call("nope")
+	assert.ErrorIs(t, err, eval.ErrUnknownFunction)
+}
+
+func TestFunctionReference(t *testing.T) {
+	var names []string
+	for _, f := range Functions() {
+		names = append(names, f.Name)
+		assert.NotEmpty(t, f.Description, f.Name)
+		assert.NotEmpty(t, f.Returns, f.Name)
+		for _, example := range f.Examples {
+			result, err := eval.Evaluate(example, nil, Call)
+			assert.NoError(t, err, example)
+			assert.Equal(t, true, result, example)
+		}
+	}
+	assert.Equal(t, Names(), names)
+}
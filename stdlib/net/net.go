@@ -0,0 +1,320 @@
+// Package net provides functions for parsing and classifying IP addresses within eval expressions, built on
+// net/netip.
+//
+// wherever an address is expected, either an IP value (as returned by `ip()`) or a string is accepted.  IP
+// values work with the `==`, `!=` and ordering operators, including against address strings.  the `in`
+// operator, testing membership of a CIDR range or list of ranges, can be added to a registry using
+// RegisterOperators:
+//
+//	registry := eval.NewOperatorRegistry()
+//	err := net.RegisterOperators(registry)
+//	program, err := registry.Compile(".client_ip in '10.0.0.0/8' || ip(.client_ip) in .trusted_ranges")
+package net
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/frozengoats/eval"
+	"github.com/frozengoats/eval/stdlib/internal/args"
+	"github.com/frozengoats/eval/stdlib/internal/library"
+)
+
+// IP is an IP address value, implementing eval.Equaler and eval.Comparer
+type IP struct {
+	addr netip.Addr
+}
+
+// ParseIP parses an IPv4 or IPv6 address
+func ParseIP(s string) (IP, error) {
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return IP{}, err
+	}
+	return IP{addr: addr}, nil
+}
+
+// Addr returns the underlying address
+func (ip IP) Addr() netip.Addr {
+	return ip.addr
+}
+
+func (ip IP) String() string {
+	return ip.addr.String()
+}
+
+// MarshalText encodes the address in its string form
+func (ip IP) MarshalText() ([]byte, error) {
+	return ip.addr.MarshalText()
+}
+
+// Equal reports whether the address is the same as another IP or address string, IPv4-mapped IPv6
+// addresses being equal to their IPv4 form
+func (ip IP) Equal(other any) (bool, error) {
+	o, err := toIP(other)
+	if err != nil {
+		return false, err
+	}
+	return ip.addr.Unmap() == o.addr.Unmap(), nil
+}
+
+// Compare orders the address against another IP or address string, IPv4 addresses ordering before IPv6
+func (ip IP) Compare(other any) (int, error) {
+	o, err := toIP(other)
+	if err != nil {
+		return 0, err
+	}
+	return ip.addr.Unmap().Compare(o.addr.Unmap()), nil
+}
+
+// In reports whether the address falls within a CIDR range (`10.0.0.0/8`) or equals an address, or any of
+// an array of these
+func (ip IP) In(other any) (bool, error) {
+	if items, err := args.Array("in", []any{other}, 0); err == nil {
+		for _, item := range items {
+			in, err := ip.In(item)
+			if err != nil || in {
+				return in, err
+			}
+		}
+		return false, nil
+	}
+
+	switch t := other.(type) {
+	case string:
+		if strings.Contains(t, "/") {
+			prefix, err := netip.ParsePrefix(t)
+			if err != nil {
+				return false, err
+			}
+			return prefix.Contains(ip.addr.Unmap()), nil
+		}
+		return ip.Equal(t)
+	case IP:
+		return ip.Equal(t)
+	case netip.Prefix:
+		return t.Contains(ip.addr.Unmap()), nil
+	default:
+		return false, fmt.Errorf("cannot test membership of %v in %T", ip, other)
+	}
+}
+
+// InOp implements the `in` operator, the left operand is an address and the right a CIDR range, address or
+// array of these
+func InOp(a any, b any) (any, error) {
+	ip, err := toIP(a)
+	if err != nil {
+		return nil, fmt.Errorf("in: %w", err)
+	}
+	return ip.In(b)
+}
+
+// RegisterOperators adds the `in` operator to the registry, at the precedence of the comparison operators
+func RegisterOperators(r *eval.OperatorRegistry) error {
+	return r.Register(eval.Operator{
+		Symbol:        "in",
+		Precedence:    eval.PrecedenceComparison,
+		Associativity: eval.AssociativityLeft,
+		Func:          InOp,
+	})
+}
+
+var functions = library.Functions{
+	"ip": {
+		Func:        ip,
+		Description: "parses an address into an IP value, which supports the equality and ordering operators",
+		Params:      []eval.Param{{Name: "address", Type: "string"}},
+		Returns:     "IP",
+		Examples:    []string{"ip('10.0.0.1') < '10.0.0.2'"},
+	},
+	"isIPv4": {
+		Func:        isIPv4,
+		Description: "reports whether the argument is an IPv4 (or IPv4-mapped IPv6) address, invalid addresses giving false",
+		Params:      []eval.Param{{Name: "address", Type: "any", Description: "an IP value or address string"}},
+		Returns:     "boolean",
+	},
+	"isIPv6": {
+		Func:        isIPv6,
+		Description: "reports whether the argument is an IPv6 address other than an IPv4-mapped one, invalid addresses giving false",
+		Params:      []eval.Param{{Name: "address", Type: "any", Description: "an IP value or address string"}},
+		Returns:     "boolean",
+	},
+	"inCIDR": {
+		Func:        inCIDR,
+		Description: "reports whether the address falls within the CIDR range, or any of an array of ranges",
+		Params: []eval.Param{
+			{Name: "address", Type: "any", Description: "an IP value or address string"},
+			{Name: "cidr", Type: "any", Description: "a CIDR range or an array of ranges"},
+		},
+		Returns:  "boolean",
+		Examples: []string{"inCIDR('10.1.2.3', '10.0.0.0/8')"},
+	},
+	"isPrivate": {
+		Func:        isPrivate,
+		Description: "reports whether the address is in a private range (RFC 1918 or RFC 4193)",
+		Params:      []eval.Param{{Name: "address", Type: "any", Description: "an IP value or address string"}},
+		Returns:     "boolean",
+	},
+	"isLoopback": {
+		Func:        isLoopback,
+		Description: "reports whether the address is a loopback address",
+		Params:      []eval.Param{{Name: "address", Type: "any", Description: "an IP value or address string"}},
+		Returns:     "boolean",
+	},
+	"prefixLength": {
+		Func:        prefixLength,
+		Description: "returns the number of bits in the prefix of a CIDR range",
+		Params:      []eval.Param{{Name: "cidr", Type: "string"}},
+		Returns:     "number",
+		Examples:    []string{"prefixLength('10.0.0.0/8') == 8"},
+	},
+}
+
+// Call executes the named network function
+func Call(name string, a ...any) (any, error) {
+	return functions.Call(name, a...)
+}
+
+// Names returns the names of all functions in the library, in sorted order
+func Names() []string {
+	return functions.Names()
+}
+
+// Functions returns the descriptions of all functions in the library ordered by name, which can be
+// rendered as a reference using eval.ReferenceMarkdown or eval.ReferenceJSON
+func Functions() []eval.Function {
+	return functions.Reference()
+}
+
+// ip parses an address into an IP value
+func ip(name string, a []any) (any, error) {
+	return ipArg(name, a)
+}
+
+// isIPv4 reports whether the argument is an IPv4 (or IPv4-mapped IPv6) address, invalid addresses yielding
+// false
+func isIPv4(name string, a []any) (any, error) {
+	addr, ok, err := validIP(name, a)
+	if err != nil || !ok {
+		return false, err
+	}
+	return addr.addr.Unmap().Is4(), nil
+}
+
+// isIPv6 reports whether the argument is an IPv6 address, excluding IPv4-mapped addresses, invalid
+// addresses yielding false
+func isIPv6(name string, a []any) (any, error) {
+	addr, ok, err := validIP(name, a)
+	if err != nil || !ok {
+		return false, err
+	}
+	return addr.addr.Is6() && !addr.addr.Is4In6(), nil
+}
+
+// inCIDR reports whether the address falls within the CIDR range, or any of an array of ranges
+func inCIDR(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	addr, err := toIP(a[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s argument 1: %w", name, err)
+	}
+
+	ranges := []any{a[1]}
+	if items, err := args.Array(name, a, 1); err == nil {
+		ranges = items
+	}
+
+	for i, r := range ranges {
+		s, ok := r.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s range %d must be a string, got %T", name, i+1, r)
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if prefix.Contains(addr.addr.Unmap()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isPrivate reports whether the address is in a private range (RFC 1918 or RFC 4193)
+func isPrivate(name string, a []any) (any, error) {
+	addr, err := ipArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return addr.addr.Unmap().IsPrivate(), nil
+}
+
+func isLoopback(name string, a []any) (any, error) {
+	addr, err := ipArg(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return addr.addr.Unmap().IsLoopback(), nil
+}
+
+// prefixLength returns the number of bits in the prefix of a CIDR range (`10.0.0.0/8` is 8)
+func prefixLength(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	s, err := args.String(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return float64(prefix.Bits()), nil
+}
+
+func ipArg(name string, a []any) (IP, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return IP{}, err
+	}
+	addr, err := toIP(a[0])
+	if err != nil {
+		return IP{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return addr, nil
+}
+
+// validIP returns the address argument, the second return value being false if it is a string which does
+// not parse as an address
+func validIP(name string, a []any) (IP, bool, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return IP{}, false, err
+	}
+
+	switch t := a[0].(type) {
+	case IP:
+		return t, true, nil
+	case string:
+		addr, err := ParseIP(t)
+		return addr, err == nil, nil
+	default:
+		return IP{}, false, fmt.Errorf("%s argument 1 must be an address, got %T", name, a[0])
+	}
+}
+
+func toIP(value any) (IP, error) {
+	switch t := value.(type) {
+	case IP:
+		return t, nil
+	case netip.Addr:
+		return IP{addr: t}, nil
+	case string:
+		return ParseIP(t)
+	default:
+		return IP{}, fmt.Errorf("%v (%T) is not an address", value, value)
+	}
+}
@@ -0,0 +1,102 @@
+package net
+
+import (
+	"testing"
+
+	"github.com/frozengoats/eval"
+	"github.com/stretchr/testify/assert"
+)
+
+var values = map[string]any{
+	".client_ip":      "10.1.2.3",
+	".v6":             "2001:db8::1",
+	".trusted_ranges": []string{"192.168.0.0/16", "10.0.0.0/8"},
+}
+
+func vLookup(key string) (any, error) {
+	return values[key], nil
+}
+
+func TestNetworkFunctions(t *testing.T) {
+	for expression, expected := range map[string]any{
+		"inCIDR(.client_ip, '10.0.0.0/8')":              true,
+		"inCIDR(.client_ip, '10.2.0.0/16')":             false,
+		"inCIDR(.client_ip, .trusted_ranges)":           true,
+		"inCIDR('::ffff:10.1.2.3', '10.0.0.0/8')":       true,
+		"ip(.client_ip) == '10.1.2.3'":                  true,
+		"'10.1.2.3' == ip(.client_ip)":                  true,
+		"ip('::ffff:10.1.2.3') == ip(.client_ip)":       true,
+		"ip(.client_ip) != '10.1.2.4'":                  true,
+		"ip('10.0.0.9') < ip('10.0.0.10')":              true,
+		"ip('255.255.255.255') < .v6":                   true,
+		"isIPv4(.client_ip) && isIPv6(.v6)":             true,
+		"isIPv4(.v6) || isIPv6('::ffff:10.1.2.3')":      false,
+		"isIPv4('not an address')":                      false,
+		"isPrivate(.client_ip) && isPrivate('fd00::1')": true,
+		"isPrivate('8.8.8.8')":                          false,
+		"isLoopback('127.0.0.1') && isLoopback('::1')":  true,
+		"prefixLength('10.0.0.0/8')":                    8.,
+		"prefixLength('2001:db8::/32')":                 32.,
+	} {
+		result, err := eval.Evaluate(expression, vLookup, Call)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestNetworkFunctionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"ip('10.0.0')",
+		"inCIDR(.client_ip, '10.0.0.0')",
+		"inCIDR(1, '10.0.0.0/8')",
+		"isPrivate('nope')",
+		"isIPv4(1)",
+		"prefixLength('10.0.0.1')",
+		"ip(.client_ip) < 5",
+	} {
+		_, err := eval.Evaluate(expression, vLookup, Call)
+		assert.Error(t, err, expression)
+	}
+
+	_, err := Call("nope")
+	assert.ErrorIs(t, err, eval.ErrUnknownFunction)
+}
+
+func TestInOperator(t *testing.T) {
+	registry := eval.NewOperatorRegistry()
+	assert.NoError(t, RegisterOperators(registry))
+
+	for expression, expected := range map[string]any{
+		".client_ip in '10.0.0.0/8'":                     true,
+		".client_ip in '10.0.0.0/8' && .v6 in '::1/128'": false,
+		"ip(.client_ip) in .trusted_ranges":              true,
+		".v6 in .trusted_ranges":                         false,
+		".client_ip in '10.1.2.3'":                       true,
+	} {
+		program, err := registry.Compile(expression)
+		assert.NoError(t, err, expression)
+		result, err := program.Evaluate(vLookup, Call)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+
+	program, err := registry.Compile("5 in '10.0.0.0/8'")
+	assert.NoError(t, err)
+	_, err = program.Evaluate(vLookup, Call)
+	assert.Error(t, err)
+}
+
+func TestFunctionReference(t *testing.T) {
+	var names []string
+	for _, f := range Functions() {
+		names = append(names, f.Name)
+		assert.NotEmpty(t, f.Description, f.Name)
+		assert.NotEmpty(t, f.Returns, f.Name)
+		for _, example := range f.Examples {
+			result, err := eval.Evaluate(example, nil, Call)
+			assert.NoError(t, err, example)
+			assert.Equal(t, true, result, example)
+		}
+	}
+	assert.Equal(t, Names(), names)
+}
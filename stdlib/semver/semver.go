@@ -0,0 +1,538 @@
+// Package semver provides functions for parsing semantic versions and checking them against constraints
+// within eval expressions.
+//
+// versions are compared using semantic version precedence rather than string ordering, by way of the
+// Version values returned by `semver()`, which work with the comparison operators (including against
+// version strings):
+//
+//	result, err := eval.Evaluate("semver(.agent.version) >= '1.10.2'", vLookup, semver.Call)
+//	result, err = eval.Evaluate("satisfies(.agent.version, '^1.2 || >=3.0.0-rc.1 <3.1')", vLookup, semver.Call)
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/frozengoats/eval"
+	"github.com/frozengoats/eval/stdlib/internal/args"
+	"github.com/frozengoats/eval/stdlib/internal/library"
+)
+
+// Version is a semantic version, implementing eval.Equaler and eval.Comparer.  build metadata is retained
+// but, as per the specification, takes no part in comparisons.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease []string
+	Build      string
+}
+
+// Parse parses a semantic version as defined by SemVer 2.0.0, with an optional `v` prefix.  the major,
+// minor and patch components are all required, partial versions and wildcards (`1.2`, `1.x`) are only
+// permitted within constraints (see ParseConstraint).
+func Parse(s string) (Version, error) {
+	v, parts, err := parsePartial(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if parts != 3 {
+		return Version{}, fmt.Errorf("invalid version %q, major, minor and patch components are required", s)
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// MarshalText encodes the version in its string form
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// Compare orders the version against another Version or version string by semantic version precedence
+func (v Version) Compare(other any) (int, error) {
+	o, err := toVersion(other)
+	if err != nil {
+		return 0, err
+	}
+	return compare(v, o), nil
+}
+
+// Equal reports whether the version has the same precedence as another Version or version string
+func (v Version) Equal(other any) (bool, error) {
+	o, err := toVersion(other)
+	if err != nil {
+		return false, err
+	}
+	return compare(v, o) == 0, nil
+}
+
+// Satisfies reports whether the version satisfies a constraint, see ParseConstraint
+func (v Version) Satisfies(constraint string) (bool, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(v), nil
+}
+
+type comparator struct {
+	op      string
+	version Version
+}
+
+func (c comparator) check(v Version) bool {
+	r := compare(v, c.version)
+	switch c.op {
+	case "<":
+		return r < 0
+	case "<=":
+		return r <= 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "!=":
+		return r != 0
+	default:
+		return r == 0
+	}
+}
+
+// Constraint is a set of alternative version ranges
+type Constraint struct {
+	alternatives [][]comparator
+}
+
+// ParseConstraint parses a version constraint.  a constraint is made up of one or more alternatives
+// separated by `||`, each of which is a space (or comma) separated list of comparisons which must all hold.
+// comparisons take the following forms, where partial versions (`1.2`, `1.x`) are permitted:
+//
+//   - `=1.2.3`, `!=1.2.3`, `>1.2.3`, `>=1.2.3`, `<1.2.3`, `<=1.2.3`
+//   - `^1.2.3` permitting changes which do not modify the left-most non-zero component (`>=1.2.3 <2.0.0`)
+//   - `~1.2.3` permitting patch level changes (`>=1.2.3 <1.3.0`)
+//   - `1.2` or `1.2.x` matching any version with the given prefix (`>=1.2.0 <1.3.0`)
+//
+// pre-release versions only satisfy an alternative which contains a comparison against a pre-release of
+// the same major, minor and patch version, thus `^1.2` is not satisfied by `2.0.0-beta`.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{}
+	for _, alt := range strings.Split(s, "||") {
+		fields := strings.Fields(strings.ReplaceAll(alt, ",", " "))
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid constraint %q, empty range", s)
+		}
+
+		// permit a space between an operator and its version (`>= 1.2`)
+		var terms []string
+		for i := 0; i < len(fields); i++ {
+			if strings.Trim(fields[i], "=<>!^~") == "" && i+1 < len(fields) {
+				terms = append(terms, fields[i]+fields[i+1])
+				i++
+				continue
+			}
+			terms = append(terms, fields[i])
+		}
+
+		var comparators []comparator
+		for _, term := range terms {
+			cmps, err := parseComparison(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			comparators = append(comparators, cmps...)
+		}
+		c.alternatives = append(c.alternatives, comparators)
+	}
+	return c, nil
+}
+
+// Check reports whether the version satisfies the constraint
+func (c *Constraint) Check(v Version) bool {
+	for _, alt := range c.alternatives {
+		if allowsPrerelease(alt, v) && all(alt, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func all(comparators []comparator, v Version) bool {
+	for _, c := range comparators {
+		if !c.check(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowsPrerelease returns true if the version is not a pre-release, or if one of the comparators refers to
+// a pre-release of the same major, minor and patch version
+func allowsPrerelease(comparators []comparator, v Version) bool {
+	if len(v.Prerelease) == 0 {
+		return true
+	}
+	for _, c := range comparators {
+		cv := c.version
+		if len(cv.Prerelease) > 0 && cv.Major == v.Major && cv.Minor == v.Minor && cv.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComparison expands a single comparison into one or more primitive comparators
+func parseComparison(term string) ([]comparator, error) {
+	op := term[:len(term)-len(strings.TrimLeft(term, "=<>!^~"))]
+	v, parts, err := parsePartial(term[len(op):])
+	if err != nil {
+		return nil, err
+	}
+
+	if parts == 0 {
+		// a wildcard (`*`, `x`) matches everything, other than through a negative or exclusive comparison
+		switch op {
+		case "", "=", "==", ">=", "<=", "^", "~":
+			return []comparator{{op: ">=", version: Version{}}}, nil
+		default:
+			return nil, fmt.Errorf("%s cannot be applied to a wildcard", op)
+		}
+	}
+
+	switch op {
+	case "", "=", "==":
+		if parts == 3 {
+			return []comparator{{op: "=", version: v}}, nil
+		}
+		return []comparator{{op: ">=", version: v}, {op: "<", version: bump(v, parts)}}, nil
+	case "!=":
+		if parts != 3 {
+			return nil, fmt.Errorf("!= requires a complete version")
+		}
+		return []comparator{{op: "!=", version: v}}, nil
+	case ">=", "<":
+		return []comparator{{op: op, version: v}}, nil
+	case ">":
+		if parts == 3 {
+			return []comparator{{op: ">", version: v}}, nil
+		}
+		return []comparator{{op: ">=", version: bump(v, parts)}}, nil
+	case "<=":
+		if parts == 3 {
+			return []comparator{{op: "<=", version: v}}, nil
+		}
+		return []comparator{{op: "<", version: bump(v, parts)}}, nil
+	case "~":
+		return []comparator{{op: ">=", version: v}, {op: "<", version: bump(v, min(parts, 2))}}, nil
+	case "^":
+		// the upper bound increments the left-most non-zero component amongst those specified
+		significant := parts
+		switch {
+		case v.Major > 0 || parts == 1:
+			significant = 1
+		case v.Minor > 0 || parts == 2:
+			significant = 2
+		}
+		return []comparator{{op: ">=", version: v}, {op: "<", version: bump(v, significant)}}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %s", op)
+	}
+}
+
+// bump returns the lowest version which is greater than every version sharing the first n components of v
+func bump(v Version, n int) Version {
+	switch n {
+	case 1:
+		return Version{Major: v.Major + 1}
+	case 2:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	default:
+		return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+	}
+}
+
+// parsePartial parses a version which may be missing trailing components, or have them replaced with a
+// wildcard (`x`, `X` or `*`), returning the number of components which were specified
+func parsePartial(s string) (Version, int, error) {
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if text == "" {
+		return Version{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+
+	var v Version
+	if i := strings.Index(text, "+"); i >= 0 {
+		v.Build = text[i+1:]
+		text = text[:i]
+		if !validIdentifiers(v.Build) {
+			return Version{}, 0, fmt.Errorf("invalid build metadata in version %q", s)
+		}
+	}
+	if i := strings.Index(text, "-"); i >= 0 {
+		prerelease := text[i+1:]
+		text = text[:i]
+		if !validIdentifiers(prerelease) || !validNumericIdentifiers(prerelease) {
+			return Version{}, 0, fmt.Errorf("invalid pre-release in version %q", s)
+		}
+		v.Prerelease = strings.Split(prerelease, ".")
+	}
+
+	components := strings.Split(text, ".")
+	if len(components) > 3 {
+		return Version{}, 0, fmt.Errorf("invalid version %q, too many components", s)
+	}
+
+	parts := 0
+	for i, c := range components {
+		if isWildcard(c) {
+			// a wildcard may only be followed by further wildcards (`1.x.x`, but not `1.x.5`)
+			for _, rest := range components[i+1:] {
+				if !isWildcard(rest) {
+					return Version{}, 0, fmt.Errorf("invalid version %q, component %d follows a wildcard", s, i+2)
+				}
+			}
+			break
+		}
+		n, err := strconv.ParseUint(c, 10, 64)
+		if err != nil || !validNumber(c) {
+			return Version{}, 0, fmt.Errorf("invalid version %q, component %d is not a number", s, i+1)
+		}
+		switch i {
+		case 0:
+			v.Major = n
+		case 1:
+			v.Minor = n
+		case 2:
+			v.Patch = n
+		}
+		parts++
+	}
+
+	if len(v.Prerelease) > 0 && parts != 3 {
+		return Version{}, 0, fmt.Errorf("invalid version %q, a pre-release requires a complete version", s)
+	}
+	return v, parts, nil
+}
+
+func isWildcard(component string) bool {
+	return component == "x" || component == "X" || component == "*"
+}
+
+// validNumber reports whether a string of digits has no leading zeros, as required of numeric components
+// and identifiers
+func validNumber(s string) bool {
+	return s == "0" || !strings.HasPrefix(s, "0")
+}
+
+// validNumericIdentifiers reports whether the numeric identifiers amongst the dot separated identifiers
+// have no leading zeros
+func validNumericIdentifiers(s string) bool {
+	for _, id := range strings.Split(s, ".") {
+		if strings.Trim(id, "0123456789") == "" && !validNumber(id) {
+			return false
+		}
+	}
+	return true
+}
+
+func validIdentifiers(s string) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compare orders two versions by semantic version precedence
+func compare(a Version, b Version) int {
+	for _, pair := range [][2]uint64{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		switch {
+		case pair[0] < pair[1]:
+			return -1
+		case pair[0] > pair[1]:
+			return 1
+		}
+	}
+
+	// a pre-release orders before the associated normal version
+	switch {
+	case len(a.Prerelease) == 0 && len(b.Prerelease) == 0:
+		return 0
+	case len(a.Prerelease) == 0:
+		return 1
+	case len(b.Prerelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if c := compareIdentifier(a.Prerelease[i], b.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a.Prerelease) < len(b.Prerelease):
+		return -1
+	case len(a.Prerelease) > len(b.Prerelease):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareIdentifier orders pre-release identifiers, numeric identifiers are compared numerically and order
+// before alpha-numeric identifiers, which are compared lexically
+func compareIdentifier(a string, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		default:
+			return 0
+		}
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+var functions = library.Functions{
+	"semver": {
+		Func:        semver,
+		Description: "parses a version string (with an optional `v` prefix) into a Version value, which the comparison operators order by semantic version precedence",
+		Params:      []eval.Param{{Name: "version", Type: "string"}},
+		Returns:     "Version",
+		Examples:    []string{"semver('1.10.2') > '1.9.0'"},
+	},
+	"isSemver": {
+		Func:        isSemver,
+		Description: "reports whether a string is a valid semantic version",
+		Params:      []eval.Param{{Name: "version", Type: "string"}},
+		Returns:     "boolean",
+	},
+	"satisfies": {
+		Func:        satisfies,
+		Description: "reports whether a version satisfies a constraint, made up of `||` separated alternatives of space separated comparisons",
+		Params: []eval.Param{
+			{Name: "version", Type: "any", Description: "a Version value or version string"},
+			{Name: "constraint", Type: "string", Description: "such as `^1.2`, `~1.2.3`, `1.2.x` or `>=1.2 <2.0`"},
+		},
+		Returns:  "boolean",
+		Examples: []string{"satisfies('1.4.0', '^1.2 || >=3.0.0')"},
+	},
+	"major":      component(func(v Version) any { return float64(v.Major) }, "returns the major version", "number"),
+	"minor":      component(func(v Version) any { return float64(v.Minor) }, "returns the minor version", "number"),
+	"patch":      component(func(v Version) any { return float64(v.Patch) }, "returns the patch version", "number"),
+	"prerelease": component(func(v Version) any { return strings.Join(v.Prerelease, ".") }, "returns the dot separated pre-release identifiers, or an empty string", "string"),
+}
+
+// Call executes the named semantic version function
+func Call(name string, a ...any) (any, error) {
+	return functions.Call(name, a...)
+}
+
+// Names returns the names of all functions in the library, in sorted order
+func Names() []string {
+	return functions.Names()
+}
+
+// Functions returns the descriptions of all functions in the library ordered by name, which can be
+// rendered as a reference using eval.ReferenceMarkdown or eval.ReferenceJSON
+func Functions() []eval.Function {
+	return functions.Reference()
+}
+
+// semver parses a version string into a Version value
+func semver(name string, a []any) (any, error) {
+	return versionArg(name, a)
+}
+
+// isSemver reports whether a string is a valid version, without failing on invalid input
+func isSemver(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	_, err := toVersion(a[0])
+	return err == nil, nil
+}
+
+// satisfies reports whether a version satisfies a constraint (`^1.2`, `>=1.2 <2.0 || 3.x`)
+func satisfies(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	v, err := toVersion(a[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	constraint, err := args.String(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := v.Satisfies(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return ok, nil
+}
+
+// component adapts a function returning a component of a version
+func component(f func(Version) any, description string, returns string) library.Function {
+	return library.Function{
+		Func: func(name string, a []any) (any, error) {
+			v, err := versionArg(name, a)
+			if err != nil {
+				return nil, err
+			}
+			return f(v), nil
+		},
+		Description: description,
+		Params:      []eval.Param{{Name: "version", Type: "any", Description: "a Version value or version string"}},
+		Returns:     returns,
+	}
+}
+
+func versionArg(name string, a []any) (Version, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return Version{}, err
+	}
+	v, err := toVersion(a[0])
+	if err != nil {
+		return Version{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+func toVersion(value any) (Version, error) {
+	switch t := value.(type) {
+	case Version:
+		return t, nil
+	case string:
+		return Parse(t)
+	default:
+		return Version{}, fmt.Errorf("%v (%T) is not a version", value, value)
+	}
+}
@@ -0,0 +1,109 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/frozengoats/eval"
+	"github.com/stretchr/testify/assert"
+)
+
+func vLookup(key string) (any, error) {
+	return map[string]any{
+		".agent.version": "1.10.2",
+		".beta":          "2.0.0-beta.2",
+	}[key], nil
+}
+
+func TestVersionFunctions(t *testing.T) {
+	for expression, expected := range map[string]any{
+		"semver(.agent.version) >= '1.10.2'":                 true,
+		"semver(.agent.version) > '1.9.9'":                   true,
+		"'1.9.9' < semver(.agent.version)":                   true,
+		"semver('v1.10.2+build.5') == .agent.version":        true,
+		"semver('1.2.0') == '1.2.0+build.7'":                 true,
+		"semver(.beta) < '2.0.0'":                            true,
+		"semver('1.0.0-alpha') < '1.0.0-alpha.1'":            true,
+		"semver('1.0.0-alpha.1') < '1.0.0-alpha.beta'":       true,
+		"semver('1.0.0-beta.2') < '1.0.0-beta.11'":           true,
+		"semver('1.0.0-rc.1') < '1.0.0'":                     true,
+		"major(.agent.version) + minor(.agent.version)":      11.,
+		"patch(.agent.version)":                              2.,
+		"prerelease(.beta)":                                  "beta.2",
+		"isSemver('1.2.3-rc.1')":                             true,
+		"isSemver('1.2.3.4')":                                false,
+		"isSemver('1.2')":                                    false,
+		"isSemver('1.2.3-rc.01')":                            false,
+		"isSemver('1.2.3-rc.0a')":                            true,
+		"isSemver('1.2.3+001')":                              true,
+		"satisfies(.agent.version, '^1.2')":                  true,
+		"satisfies(.agent.version, '~1.9')":                  false,
+		"satisfies(.agent.version, '>=1.2 <2.0')":            true,
+		"satisfies(.agent.version, '>= 1.2, < 1.10')":        false,
+		"satisfies(.agent.version, '1.10.x')":                true,
+		"satisfies(.agent.version, '<1.0 || 1.10')":          true,
+		"satisfies(.agent.version, '>1.10')":                 false,
+		"satisfies(.agent.version, '<=1.10')":                true,
+		"satisfies(.agent.version, '!=1.10.2')":              false,
+		"satisfies(.agent.version, '*')":                     true,
+		"satisfies(.beta, '^1.2')":                           false,
+		"satisfies(.beta, '<2.0.0')":                         false,
+		"satisfies(.beta, '>=2.0.0-beta.1')":                 true,
+		"satisfies(.beta, '>=2.0.0-beta.3 || ^2.0.0-alpha')": true,
+		"satisfies('0.2.5', '^0.2.3')":                       true,
+		"satisfies('0.3.0', '^0.2.3')":                       false,
+		"satisfies('0.0.4', '^0.0.3')":                       false,
+	} {
+		result, err := eval.Evaluate(expression, vLookup, Call)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestVersionFunctionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"semver('1.2.3.4')",
+		"semver('1.a')",
+		"semver('1.2-beta')",
+		"semver('1.2')",
+		"semver('1.x')",
+		"semver('1.x.5')",
+		"semver('*')",
+		"semver('01.2.3')",
+		"semver('1.2.3-01')",
+		"semver('1.10.2') > '1.9'",
+		"satisfies('1.2.3', '1.x.5')",
+		"satisfies('1.2.3', '^01.2')",
+		"semver(1)",
+		"satisfies('1.2.3', '')",
+		"satisfies('1.2.3', '=>1.2')",
+		"satisfies('1.2.3', '!=1.2')",
+		"semver('1.2.3') < 5",
+	} {
+		_, err := eval.Evaluate(expression, vLookup, Call)
+		assert.Error(t, err, expression)
+	}
+
+	_, err := Call("nope")
+	assert.ErrorIs(t, err, eval.ErrUnknownFunction)
+}
+
+func TestVersionString(t *testing.T) {
+	v, err := Parse("v1.2.3-rc.1+sha.abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.2.3-rc.1+sha.abc", v.String())
+}
+
+func TestFunctionReference(t *testing.T) {
+	var names []string
+	for _, f := range Functions() {
+		names = append(names, f.Name)
+		assert.NotEmpty(t, f.Description, f.Name)
+		assert.NotEmpty(t, f.Returns, f.Name)
+		for _, example := range f.Examples {
+			result, err := eval.Evaluate(example, nil, Call)
+			assert.NoError(t, err, example)
+			assert.Equal(t, true, result, example)
+		}
+	}
+	assert.Equal(t, Names(), names)
+}
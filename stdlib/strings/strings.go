@@ -0,0 +1,506 @@
+// Package strings provides string functions for eval expressions, covering case conversion, trimming,
+// splitting and joining, searching, padding, formatting and edit distance.  lengths, padding widths and
+// edit distances are counted in characters rather than bytes.
+//
+//	result, err := eval.Evaluate("upper(trim(.name))", vLookup, eval.ChainFunctions(strings.Call, hostFunctions))
+package strings
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/frozengoats/eval"
+	"github.com/frozengoats/eval/stdlib/internal/args"
+	"github.com/frozengoats/eval/stdlib/internal/library"
+)
+
+// MaxLength is the maximum length in bytes of a string produced by repeat, padLeft and padRight, checked
+// before the string is allocated.  when called through CallContext by an evaluation whose limits set
+// MaxStringLength, that limit applies instead.
+const MaxLength = 1 << 20
+
+var functions = library.Functions{
+	"len": {
+		Func:        length,
+		Description: "returns the number of characters in a string, or the number of items in an array or mapping",
+		Params:      []eval.Param{{Name: "value", Type: "any", Description: "a string, array or mapping"}},
+		Returns:     "number",
+		Examples:    []string{"len('héllo') == 5"},
+	},
+	"lower": {
+		Func:        lower,
+		Description: "converts a string to lower case",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"upper": {
+		Func:        upper,
+		Description: "converts a string to upper case",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "string",
+	},
+	"trim": {
+		Func:        trim,
+		Description: "removes leading and trailing whitespace, or the characters of the cutset",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "cutset", Type: "string", Description: "optional, the characters to remove"},
+		},
+		Returns:  "string",
+		Examples: []string{"trim('xxabcxx', 'x') == 'abc'"},
+	},
+	"split": {
+		Func:        split,
+		Description: "splits a string around each occurrence of the separator",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "sep", Type: "string"},
+		},
+		Returns:  "array of string",
+		Examples: []string{"join(split('a,b', ','), '+') == 'a+b'"},
+	},
+	"join": {
+		Func:        join,
+		Description: "concatenates an array of strings, placing the separator between them",
+		Params: []eval.Param{
+			{Name: "items", Type: "array of string"},
+			{Name: "sep", Type: "string"},
+		},
+		Returns: "string",
+	},
+	"replace": {
+		Func:        replace,
+		Description: "replaces occurrences of old with new, all of them unless a count is supplied",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "old", Type: "string"},
+			{Name: "new", Type: "string"},
+			{Name: "count", Type: "integer", Description: "optional, the number of occurrences to replace"},
+		},
+		Returns:  "string",
+		Examples: []string{"replace('aaa', 'a', 'b', 2) == 'bba'"},
+	},
+	"startsWith": {
+		Func:        startsWith,
+		Description: "reports whether a string begins with the prefix",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "prefix", Type: "string"},
+		},
+		Returns: "boolean",
+	},
+	"endsWith": {
+		Func:        endsWith,
+		Description: "reports whether a string ends with the suffix",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "suffix", Type: "string"},
+		},
+		Returns: "boolean",
+	},
+	"contains": {
+		Func:        contains,
+		Description: "reports whether the substring appears within a string",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "substr", Type: "string"},
+		},
+		Returns: "boolean",
+	},
+	"padLeft": {
+		FuncContext: padLeft,
+		Description: "pads the start of a string up to the width in characters, with spaces or the padding string",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "width", Type: "integer"},
+			{Name: "padding", Type: "string", Description: "optional, repeated as needed"},
+		},
+		Returns:  "string",
+		Examples: []string{"padLeft('7', 3, '0') == '007'"},
+	},
+	"padRight": {
+		FuncContext: padRight,
+		Description: "pads the end of a string up to the width in characters, with spaces or the padding string",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "width", Type: "integer"},
+			{Name: "padding", Type: "string", Description: "optional, repeated as needed"},
+		},
+		Returns:  "string",
+		Examples: []string{"padRight('ab', 5, '.-') == 'ab.-.'"},
+	},
+	"format": {
+		Func:        format,
+		Description: "formats the values according to a Go fmt style format string, numbers being formatted with `%v` or `%g` rather than `%d`",
+		Params: []eval.Param{
+			{Name: "format", Type: "string"},
+			{Name: "values", Type: "any"},
+		},
+		Variadic: true,
+		Returns:  "string",
+		Examples: []string{"format('%v-%s', 3, 'x') == '3-x'"},
+	},
+	"printf": {
+		Func:        format,
+		Description: "an alias of format",
+		Params: []eval.Param{
+			{Name: "format", Type: "string"},
+			{Name: "values", Type: "any"},
+		},
+		Variadic: true,
+		Returns:  "string",
+	},
+	"repeat": {
+		FuncContext: repeat,
+		Description: "repeats a string count times, up to a result of MaxLength bytes, or the evaluation's maximum string length",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "count", Type: "integer"},
+		},
+		Returns:  "string",
+		Examples: []string{"repeat('ab', 3) == 'ababab'"},
+	},
+	"levenshtein": {
+		Func:        levenshtein,
+		Description: "returns the edit distance between two strings, counted in characters",
+		Params: []eval.Param{
+			{Name: "a", Type: "string"},
+			{Name: "b", Type: "string"},
+		},
+		Returns:  "number",
+		Examples: []string{"levenshtein('kitten', 'sitting') == 3"},
+	},
+}
+
+// Call executes the named string function
+func Call(name string, a ...any) (any, error) {
+	return functions.Call(name, a...)
+}
+
+// CallContext executes the named string function, it is compatible with eval.FunctionCallContext and
+// limits the strings produced by repeat, padLeft and padRight to the MaxStringLength of the evaluation
+func CallContext(ctx context.Context, name string, a []any, namedArgs map[string]any) (any, error) {
+	return functions.CallContext(ctx, name, a, namedArgs)
+}
+
+// Names returns the names of all functions in the library, in sorted order
+func Names() []string {
+	return functions.Names()
+}
+
+// Functions returns the descriptions of all functions in the library ordered by name, which can be
+// rendered as a reference using eval.ReferenceMarkdown or eval.ReferenceJSON
+func Functions() []eval.Function {
+	return functions.Reference()
+}
+
+// length returns the number of characters in a string, or the number of items in an array or mapping
+func length(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+
+	switch t := a[0].(type) {
+	case string:
+		return float64(utf8.RuneCountInString(t)), nil
+	case map[string]any:
+		return float64(len(t)), nil
+	default:
+		items, err := args.Array(name, a, 0)
+		if err != nil {
+			return nil, fmt.Errorf("%s argument 1 must be a string, array or mapping, got %T", name, a[0])
+		}
+		return float64(len(items)), nil
+	}
+}
+
+func lower(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	s, err := args.String(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(s), nil
+}
+
+func upper(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	s, err := args.String(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToUpper(s), nil
+}
+
+// trim removes leading and trailing whitespace, or the characters of the optional cutset
+func trim(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 2); err != nil {
+		return nil, err
+	}
+	s, err := args.String(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(a) == 1 {
+		return strings.TrimSpace(s), nil
+	}
+
+	cutset, err := args.String(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Trim(s, cutset), nil
+}
+
+func split(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	s, err := args.String(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := args.String(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(s, sep)
+	result := make([]any, 0, len(parts))
+	for _, p := range parts {
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// join concatenates the items of an array of strings, placing the separator between them
+func join(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	items, err := args.Array(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := args.String(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]string, 0, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s item %d must be a string, got %T", name, i+1, item)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// replace replaces occurrences of old with new, all of them unless a count is supplied
+func replace(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 3, 4); err != nil {
+		return nil, err
+	}
+	var strs [3]string
+	for i := range strs {
+		s, err := args.String(name, a, i)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+
+	n := -1
+	if len(a) == 4 {
+		count, err := args.Int(name, a, 3)
+		if err != nil {
+			return nil, err
+		}
+		n = count
+	}
+	return strings.Replace(strs[0], strs[1], strs[2], n), nil
+}
+
+func startsWith(name string, a []any) (any, error) {
+	s, prefix, err := stringPair(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(s, prefix), nil
+}
+
+func endsWith(name string, a []any) (any, error) {
+	s, suffix, err := stringPair(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(s, suffix), nil
+}
+
+func contains(name string, a []any) (any, error) {
+	s, substr, err := stringPair(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Contains(s, substr), nil
+}
+
+func padLeft(ctx context.Context, name string, a []any) (any, error) {
+	s, padding, err := pad(ctx, name, a)
+	if err != nil {
+		return nil, err
+	}
+	return padding + s, nil
+}
+
+func padRight(ctx context.Context, name string, a []any) (any, error) {
+	s, padding, err := pad(ctx, name, a)
+	if err != nil {
+		return nil, err
+	}
+	return s + padding, nil
+}
+
+// format formats its arguments according to a fmt style format string (numbers are float64, thus `%v` or
+// `%g` should be used in place of `%d`)
+func format(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, -1); err != nil {
+		return nil, err
+	}
+	f, err := args.String(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf(f, a[1:]...), nil
+}
+
+func repeat(ctx context.Context, name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	s, err := args.String(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	n, err := args.Int(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("%s count must not be negative", name)
+	}
+	if err := checkLength(ctx, name, len(s), n); err != nil {
+		return nil, err
+	}
+	return strings.Repeat(s, n), nil
+}
+
+// checkLength ensures that count repetitions of size bytes stay within the MaxStringLength of the
+// evaluation's limits, or MaxLength when no limit is set
+func checkLength(ctx context.Context, name string, size int, count int) error {
+	if limits := eval.LimitsFromContext(ctx); limits != nil && limits.MaxStringLength > 0 {
+		if size > 0 && count > limits.MaxStringLength/size {
+			err := &eval.LimitError{Limit: eval.ErrStringTooLong, Max: limits.MaxStringLength}
+			return fmt.Errorf("%s result would exceed the maximum length: %w", name, err)
+		}
+		return nil
+	}
+
+	if size > 0 && count > MaxLength/size {
+		return fmt.Errorf("%s result would exceed the maximum length of %d bytes", name, MaxLength)
+	}
+	return nil
+}
+
+// levenshtein returns the edit distance between two strings, counted in characters
+func levenshtein(name string, a []any) (any, error) {
+	first, second, err := stringPair(name, a)
+	if err != nil {
+		return nil, err
+	}
+
+	s, t := []rune(first), []rune(second)
+	prev := make([]int, len(t)+1)
+	cur := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return float64(prev[len(t)]), nil
+}
+
+func stringPair(name string, a []any) (string, string, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return "", "", err
+	}
+	first, err := args.String(name, a, 0)
+	if err != nil {
+		return "", "", err
+	}
+	second, err := args.String(name, a, 1)
+	if err != nil {
+		return "", "", err
+	}
+	return first, second, nil
+}
+
+// pad returns the string and the padding required to bring it up to the requested width, padding with
+// spaces unless a padding string is supplied
+func pad(ctx context.Context, name string, a []any) (string, string, error) {
+	if err := args.Count(name, a, 2, 3); err != nil {
+		return "", "", err
+	}
+	s, err := args.String(name, a, 0)
+	if err != nil {
+		return "", "", err
+	}
+	width, err := args.Int(name, a, 1)
+	if err != nil {
+		return "", "", err
+	}
+
+	fill := " "
+	if len(a) == 3 {
+		fill, err = args.String(name, a, 2)
+		if err != nil {
+			return "", "", err
+		}
+		if len(fill) == 0 {
+			return "", "", fmt.Errorf("%s padding must not be empty", name)
+		}
+	}
+
+	missing := width - utf8.RuneCountInString(s)
+	if missing <= 0 {
+		return s, "", nil
+	}
+
+	// the fill is repeated enough times to cover the missing characters, then cut down to size
+	count := (missing + utf8.RuneCountInString(fill) - 1) / utf8.RuneCountInString(fill)
+	if err := checkLength(ctx, name, len(fill), count); err != nil {
+		return "", "", err
+	}
+	padding := []rune(strings.Repeat(fill, count))
+	return s, string(padding[:missing]), nil
+}
@@ -0,0 +1,116 @@
+package strings
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/frozengoats/eval"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringFunctions(t *testing.T) {
+	vLookup := func(key string) (any, error) {
+		return []any{"a", "b", "c"}, nil
+	}
+
+	for expression, expected := range map[string]any{
+		"len('héllo')":                     5.,
+		"len(.list)":                       3.,
+		"lower('ABC') + upper('def')":      "abcDEF",
+		"trim('  abc  ')":                  "abc",
+		"trim('xxabcxx', 'x')":             "abc",
+		"split('a,b,c', ',')":              []any{"a", "b", "c"},
+		"join(.list, '-')":                 "a-b-c",
+		"join(split('a b', ' '), '+')":     "a+b",
+		"replace('aaa', 'a', 'b')":         "bbb",
+		"replace('aaa', 'a', 'b', 2)":      "bba",
+		"startsWith('abc', 'ab')":          true,
+		"endsWith('abc', 'ab')":            false,
+		"contains('abc', 'b')":             true,
+		"padLeft('7', 3, '0')":             "007",
+		"padRight('ab', 5, '.-')":          "ab.-.",
+		"padLeft('abc', 2)":                "abc",
+		"format('%v-%s', 3, 'x')":          "3-x",
+		"printf('%05.1f', 3.14159)":        "003.1",
+		"repeat('ab', 3)":                  "ababab",
+		"levenshtein('kitten', 'sitting')": 3.,
+		"levenshtein('', 'abc')":           3.,
+	} {
+		result, err := eval.Evaluate(expression, vLookup, Call)
+		assert.NoError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestStringFunctionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"lower(1)",
+		"upper('a', 'b')",
+		"join(.list, 1)",
+		"repeat('a', 1.5)",
+		"repeat('a', 0 - 1)",
+		"repeat('a', 1e10)",
+		"repeat('ab', 524289)",
+		"padLeft('a', 1e10)",
+		"padRight('a', 2000000, 'xy')",
+		"len(true)",
+		"split('a')",
+	} {
+		_, err := eval.Evaluate(expression, func(key string) (any, error) {
+			return []any{1.}, nil
+		}, Call)
+		assert.Error(t, err, expression)
+	}
+
+	// items are numbered from 1, as arguments are
+	_, err := Call("join", []any{"a", 1.}, "-")
+	assert.EqualError(t, err, "join item 2 must be a string, got float64")
+
+	_, err = Call("nope")
+	assert.ErrorIs(t, err, eval.ErrUnknownFunction)
+	assert.Contains(t, Names(), "levenshtein")
+	assert.True(t, slices.IsSorted(Names()))
+}
+
+func TestStringLimits(t *testing.T) {
+	env := &eval.Env{FunctionsContext: CallContext, Limits: &eval.Limits{MaxStringLength: 10}}
+	result, err := eval.EvaluateWith("repeat('ab', 5) + padLeft('', 0)", env)
+	assert.NoError(t, err)
+	assert.Equal(t, "ababababab", result)
+
+	// the evaluation's limit is checked before the string is allocated
+	for _, expression := range []string{"repeat('ab', 6)", "padLeft('a', 11)", "padRight('a', 1e10, 'xy')"} {
+		_, err = eval.EvaluateWith(expression, env)
+		assert.ErrorIs(t, err, eval.ErrStringTooLong, expression)
+	}
+
+	// a limit above MaxLength replaces it
+	env.Limits.MaxStringLength = 2 * MaxLength
+	result, err = eval.EvaluateWith("len(repeat('a', 1048577))", env)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(MaxLength+1), result)
+
+	// MaxLength applies when the evaluation has no string limit
+	env.Limits.MaxStringLength = 0
+	_, err = eval.EvaluateWith("repeat('a', 1048577)", env)
+	assert.EqualError(t, err, "repeat result would exceed the maximum length of 1048576 bytes")
+
+	_, err = CallContext(context.Background(), "repeat", []any{"a", 1.}, map[string]any{"n": 1.})
+	assert.EqualError(t, err, "function repeat does not accept named arguments")
+}
+
+func TestFunctionReference(t *testing.T) {
+	var names []string
+	for _, f := range Functions() {
+		names = append(names, f.Name)
+		assert.NotEmpty(t, f.Description, f.Name)
+		assert.NotEmpty(t, f.Returns, f.Name)
+		for _, example := range f.Examples {
+			result, err := eval.Evaluate(example, nil, Call)
+			assert.NoError(t, err, example)
+			assert.Equal(t, true, result, example)
+		}
+	}
+	assert.Equal(t, Names(), names)
+}
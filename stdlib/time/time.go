@@ -0,0 +1,386 @@
+// Package time is an optional library of date and time functions for eval expressions.  functions return
+// native time.Time and time.Duration values, which the eval operators understand (`now() - .lastSeen > 5m`).
+//
+// the current time is obtained from a Clock, allowing tests to control the result of `now()`:
+//
+//	lib := time.New(time.FixedClock(ts))
+//	result, err := eval.Evaluate("hour(now())", vLookup, lib.Call)
+//
+// wherever a time is expected, an RFC 3339 formatted string is also accepted, and wherever a duration is
+// expected, a duration string (`'1h30m'`) is also accepted.
+package time
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"time"
+
+	"github.com/frozengoats/eval"
+	"github.com/frozengoats/eval/stdlib/internal/args"
+	"github.com/frozengoats/eval/stdlib/internal/library"
+)
+
+// Clock provides the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is a Clock returning the current system time
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// FixedClock returns a Clock which always returns the supplied time
+func FixedClock(t time.Time) Clock {
+	return fixedClock(t)
+}
+
+// layouts are the named layouts accepted by parseTime and formatTime, any other layout is interpreted as a
+// Go reference time layout
+var layouts = map[string]string{
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC822":      time.RFC822,
+	"RFC850":      time.RFC850,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+var functions = library.Functions{
+	"parseTime": {
+		Func:        parseTime,
+		Description: "parses a string as a time using the layout",
+		Params: []eval.Param{
+			{Name: "s", Type: "string"},
+			{Name: "layout", Type: "string", Description: "optional, a Go reference layout or a named layout such as `DateOnly`, RFC 3339 by default"},
+		},
+		Returns:  "time",
+		Examples: []string{"year(parseTime('2024-03-01', 'DateOnly')) == 2024"},
+	},
+	"formatTime": {
+		Func:        formatTime,
+		Description: "formats a time using the layout",
+		Params: []eval.Param{
+			{Name: "t", Type: "any", Description: "a time or RFC 3339 string"},
+			{Name: "layout", Type: "string", Description: "optional, a Go reference layout or a named layout such as `DateOnly`, RFC 3339 by default"},
+		},
+		Returns: "string",
+	},
+	"parseDuration": {
+		Func:        parseDuration,
+		Description: "parses a duration string such as `1h30m`",
+		Params:      []eval.Param{{Name: "s", Type: "string"}},
+		Returns:     "duration",
+		Examples:    []string{"parseDuration('1h30m') == 90m"},
+	},
+	"addDuration": {
+		Func:        addDuration,
+		Description: "adds a duration to a time",
+		Params: []eval.Param{
+			{Name: "t", Type: "any", Description: "a time or RFC 3339 string"},
+			{Name: "d", Type: "any", Description: "a duration or duration string"},
+		},
+		Returns: "time",
+	},
+	"truncate": {
+		Func:        truncate,
+		Description: "rounds a time down to a multiple of the duration since the zero time",
+		Params: []eval.Param{
+			{Name: "t", Type: "any", Description: "a time or RFC 3339 string"},
+			{Name: "d", Type: "any", Description: "a duration or duration string"},
+		},
+		Returns: "time",
+	},
+	"inZone": {
+		Func:        inZone,
+		Description: "converts a time to the named IANA time zone, such as `America/New_York`, `UTC` or `Local`",
+		Params: []eval.Param{
+			{Name: "t", Type: "any", Description: "a time or RFC 3339 string"},
+			{Name: "zone", Type: "string"},
+		},
+		Returns: "time",
+	},
+	"unix": {
+		Func:        unix,
+		Description: "returns the number of seconds since the unix epoch, including fractional seconds",
+		Params:      []eval.Param{{Name: "t", Type: "any", Description: "a time or RFC 3339 string"}},
+		Returns:     "number",
+	},
+	"fromUnix": {
+		Func:        fromUnix,
+		Description: "returns the UTC time a number of seconds after the unix epoch, which must fall within the years 1 to 9999",
+		Params:      []eval.Param{{Name: "seconds", Type: "number"}},
+		Returns:     "time",
+		Examples:    []string{"unix(fromUnix(1700000000)) == 1700000000"},
+	},
+	"year":    component(func(t time.Time) any { return float64(t.Year()) }, "returns the year of a time", "number"),
+	"month":   component(func(t time.Time) any { return float64(t.Month()) }, "returns the month of a time, from 1 to 12", "number"),
+	"day":     component(func(t time.Time) any { return float64(t.Day()) }, "returns the day of the month of a time", "number"),
+	"hour":    component(func(t time.Time) any { return float64(t.Hour()) }, "returns the hour of a time, from 0 to 23", "number"),
+	"minute":  component(func(t time.Time) any { return float64(t.Minute()) }, "returns the minute of a time", "number"),
+	"second":  component(func(t time.Time) any { return float64(t.Second()) }, "returns the second of a time", "number"),
+	"weekday": component(func(t time.Time) any { return t.Weekday().String() }, "returns the English name of the day of the week of a time (`Monday`)", "string"),
+}
+
+// Library is a time function library bound to a Clock
+type Library struct {
+	clock     Clock
+	functions library.Functions
+}
+
+// New returns a library which obtains the current time from the clock, or the system clock if nil
+func New(clock Clock) *Library {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	l := &Library{
+		clock:     clock,
+		functions: maps.Clone(functions),
+	}
+	l.functions["now"] = library.Function{
+		Func:        l.now,
+		Description: "returns the current time",
+		Returns:     "time",
+	}
+	return l
+}
+
+var defaultLibrary = New(SystemClock{})
+
+// Call executes the named time function using the system clock
+func Call(name string, a ...any) (any, error) {
+	return defaultLibrary.Call(name, a...)
+}
+
+// Call executes the named time function, it is compatible with eval.FunctionCall and returns
+// eval.ErrUnknownFunction for names it does not implement
+func (l *Library) Call(name string, a ...any) (any, error) {
+	return l.functions.Call(name, a...)
+}
+
+// Names returns the names of all functions in the library, in sorted order
+func Names() []string {
+	return defaultLibrary.functions.Names()
+}
+
+// Functions returns the descriptions of all functions in the library ordered by name, which can be
+// rendered as a reference using eval.ReferenceMarkdown or eval.ReferenceJSON
+func Functions() []eval.Function {
+	return defaultLibrary.functions.Reference()
+}
+
+func (l *Library) now(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 0, 0); err != nil {
+		return nil, err
+	}
+	return l.clock.Now(), nil
+}
+
+// parseTime parses a string using the optional layout, RFC 3339 by default
+func parseTime(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 2); err != nil {
+		return nil, err
+	}
+	s, err := args.String(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := layoutArg(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return t, nil
+}
+
+// formatTime formats a time using the optional layout, RFC 3339 by default
+func formatTime(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 2); err != nil {
+		return nil, err
+	}
+	t, err := timeArg(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := layoutArg(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+	return t.Format(layout), nil
+}
+
+func parseDuration(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	return durationArg(name, a, 0)
+}
+
+func addDuration(name string, a []any) (any, error) {
+	t, d, err := timeAndDuration(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return t.Add(d), nil
+}
+
+// truncate rounds a time down to a multiple of the duration (since the zero time)
+func truncate(name string, a []any) (any, error) {
+	t, d, err := timeAndDuration(name, a)
+	if err != nil {
+		return nil, err
+	}
+	return t.Truncate(d), nil
+}
+
+// inZone converts a time to the named IANA time zone (`America/New_York`, `UTC`, `Local`)
+func inZone(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return nil, err
+	}
+	t, err := timeArg(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	zone, err := args.String(name, a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return t.In(loc), nil
+}
+
+// unix returns the number of seconds since the unix epoch, including fractional seconds
+func unix(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	t, err := timeArg(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	return float64(t.Unix()) + float64(t.Nanosecond())/float64(time.Second), nil
+}
+
+// the range of times accepted by fromUnix, being the years representable in RFC 3339
+var (
+	minUnix = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	maxUnix = time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+)
+
+// fromUnix returns the UTC time for a number of seconds since the unix epoch, which must fall within the
+// years 1 to 9999
+func fromUnix(name string, a []any) (any, error) {
+	if err := args.Count(name, a, 1, 1); err != nil {
+		return nil, err
+	}
+	seconds, err := args.Number(name, a, 0)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(seconds) || seconds < float64(minUnix) || seconds >= float64(maxUnix) {
+		return nil, fmt.Errorf("%s: %v is outside the years 1 to 9999", name, seconds)
+	}
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(math.Round(fraction*float64(time.Second)))).UTC(), nil
+}
+
+// component adapts a function returning a component of a time
+func component(f func(time.Time) any, description string, returns string) library.Function {
+	return library.Function{
+		Func: func(name string, a []any) (any, error) {
+			if err := args.Count(name, a, 1, 1); err != nil {
+				return nil, err
+			}
+			t, err := timeArg(name, a, 0)
+			if err != nil {
+				return nil, err
+			}
+			return f(t), nil
+		},
+		Description: description,
+		Params:      []eval.Param{{Name: "t", Type: "any", Description: "a time or RFC 3339 string"}},
+		Returns:     returns,
+	}
+}
+
+func timeAndDuration(name string, a []any) (time.Time, time.Duration, error) {
+	if err := args.Count(name, a, 2, 2); err != nil {
+		return time.Time{}, 0, err
+	}
+	t, err := timeArg(name, a, 0)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	d, err := durationArg(name, a, 1)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return t, d, nil
+}
+
+func timeArg(name string, a []any, i int) (time.Time, error) {
+	switch t := a[i].(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		tm, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%s argument %d: %w", name, i+1, err)
+		}
+		return tm, nil
+	default:
+		return time.Time{}, fmt.Errorf("%s argument %d must be a time, got %T", name, i+1, a[i])
+	}
+}
+
+func durationArg(name string, a []any, i int) (time.Duration, error) {
+	switch t := a[i].(type) {
+	case time.Duration:
+		return t, nil
+	case string:
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return 0, fmt.Errorf("%s argument %d: %w", name, i+1, err)
+		}
+		return d, nil
+	default:
+		return 0, fmt.Errorf("%s argument %d must be a duration, got %T", name, i+1, a[i])
+	}
+}
+
+func layoutArg(name string, a []any, i int) (string, error) {
+	if len(a) <= i {
+		return time.RFC3339, nil
+	}
+
+	layout, err := args.String(name, a, i)
+	if err != nil {
+		return "", err
+	}
+	if named, ok := layouts[layout]; ok {
+		return named, nil
+	}
+	return layout, nil
+}
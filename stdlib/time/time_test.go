@@ -0,0 +1,114 @@
+package time
+
+import (
+	"testing"
+	"time"
+
+	"github.com/frozengoats/eval"
+	"github.com/stretchr/testify/assert"
+)
+
+var fixed = time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+
+func TestTimeFunctions(t *testing.T) {
+	lib := New(FixedClock(fixed))
+	lastSeen := fixed.Add(-10 * time.Minute)
+	vLookup := func(key string) (any, error) {
+		return lastSeen, nil
+	}
+
+	for expression, expected := range map[string]any{
+		"now()":                  fixed,
+		".lastSeen < now() - 5m": true,
+		"now() - .lastSeen":      10 * time.Minute,
+		"parseTime('2025-03-14T15:09:26Z') == now()": true,
+		"parseTime('2025-03-14', 'DateOnly')":        time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC),
+		"formatTime(now(), 'Kitchen')":               "3:09PM",
+		"formatTime(now(), '2006/01/02')":            "2025/03/14",
+		"formatTime(now())":                          "2025-03-14T15:09:26Z",
+		"parseDuration('1h30m')":                     90 * time.Minute,
+		"addDuration(now(), 1h) == now() + 1h":       true,
+		"addDuration('2025-03-14T15:09:26Z', '-1h')": fixed.Add(-time.Hour),
+		"formatTime(truncate(now(), 1h))":            "2025-03-14T15:00:00Z",
+		"hour(inZone(now(), 'America/New_York'))":    11.,
+		"weekday(now())":                             "Friday",
+		"year(now()) + month(now()) + day(now())":    2042.,
+		"minute(now()) + second(now())":              35.,
+		"fromUnix(unix(now())) == now()":             true,
+	} {
+		result, err := eval.Evaluate(expression, vLookup, lib.Call)
+		assert.NoError(t, err, expression)
+		if expectedTime, ok := expected.(time.Time); ok {
+			assert.True(t, expectedTime.Equal(result.(time.Time)), expression)
+			continue
+		}
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestTimeFunctionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"now(1)",
+		"parseTime('yesterday')",
+		"hour(5)",
+		"addDuration(now(), 'soon')",
+		"inZone(now(), 'Nowhere/Special')",
+		"fromUnix(1e300)",
+		"fromUnix(-1e12)",
+		"fromUnix(253402300800)",
+	} {
+		_, err := eval.Evaluate(expression, nil, Call)
+		assert.Error(t, err, expression)
+	}
+
+	_, err := Call("nope")
+	assert.ErrorIs(t, err, eval.ErrUnknownFunction)
+}
+
+func TestUnixRange(t *testing.T) {
+	// times outside the range of int64 nanoseconds (1678 to 2262) are converted exactly
+	for _, tm := range []time.Time{
+		time.Date(1500, 6, 1, 12, 0, 0, 500_000_000, time.UTC),
+		time.Date(2300, 1, 1, 0, 0, 0, 250_000_000, time.UTC),
+		time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+	} {
+		seconds, err := Call("unix", tm)
+		assert.NoError(t, err, tm)
+		assert.Equal(t, float64(tm.Unix())+float64(tm.Nanosecond())/1e9, seconds, tm)
+
+		result, err := Call("fromUnix", seconds)
+		assert.NoError(t, err, tm)
+		assert.True(t, tm.Equal(result.(time.Time)), tm)
+	}
+
+	result, err := Call("fromUnix", -1.5)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(1969, 12, 31, 23, 59, 58, 500_000_000, time.UTC), result)
+}
+
+func TestSystemClock(t *testing.T) {
+	result, err := Call("now")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), result.(time.Time), time.Minute)
+
+	// a nil clock defaults to the system clock
+	result, err = New(nil).Call("now")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), result.(time.Time), time.Minute)
+}
+
+func TestFunctionReference(t *testing.T) {
+	var names []string
+	for _, f := range Functions() {
+		names = append(names, f.Name)
+		assert.NotEmpty(t, f.Description, f.Name)
+		assert.NotEmpty(t, f.Returns, f.Name)
+		for _, example := range f.Examples {
+			result, err := eval.Evaluate(example, nil, Call)
+			assert.NoError(t, err, example)
+			assert.Equal(t, true, result, example)
+		}
+	}
+	assert.Equal(t, Names(), names)
+}
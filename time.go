@@ -0,0 +1,63 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// isDurationLiteral returns true if the unquoted text is a duration literal such as `5m`, `1h30m` or `250ms`
+func isDurationLiteral(text string) bool {
+	if len(text) == 0 || text[0] < '0' || text[0] > '9' {
+		return false
+	}
+
+	_, err := time.ParseDuration(text)
+	return err == nil
+}
+
+// asTime returns the time represented by a value, which is either a time.Time, or a string containing an
+// RFC 3339 formatted timestamp
+func asTime(value any) (time.Time, bool) {
+	switch t := value.(type) {
+	case time.Time:
+		return t, true
+	case string:
+		tm, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return tm, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// toDuration converts a number of nanoseconds resulting from an operation to a duration, failing rather than
+// wrapping when it is outside the range of a duration
+func toDuration(ns float64, operation string) (time.Duration, error) {
+	if math.IsNaN(ns) || ns >= math.MaxInt64 || ns < math.MinInt64 {
+		return 0, fmt.Errorf("duration %s overflows", operation)
+	}
+	return time.Duration(ns), nil
+}
+
+// addDurations adds a pair of durations, failing rather than wrapping when the result is outside the range of
+// a duration
+func addDurations(a time.Duration, b time.Duration) (time.Duration, error) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, fmt.Errorf("duration addition overflows")
+	}
+	return sum, nil
+}
+
+// subtractDurations subtracts a duration from another, failing rather than wrapping when the result is
+// outside the range of a duration
+func subtractDurations(a time.Duration, b time.Duration) (time.Duration, error) {
+	difference := a - b
+	if (b > 0 && difference > a) || (b < 0 && difference < a) {
+		return 0, fmt.Errorf("duration subtraction overflows")
+	}
+	return difference, nil
+}
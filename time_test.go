@@ -0,0 +1,30 @@
+package eval
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDurationLiteral(t *testing.T) {
+	assert.True(t, isDurationLiteral("5m"))
+	assert.True(t, isDurationLiteral("1h30m"))
+	assert.True(t, isDurationLiteral("250ms"))
+	assert.True(t, isDurationLiteral("1.5h"))
+	assert.False(t, isDurationLiteral("m5"))
+	assert.False(t, isDurationLiteral("5"))
+	assert.False(t, isDurationLiteral("five"))
+	assert.False(t, isDurationLiteral(""))
+}
+
+func TestAsTime(t *testing.T) {
+	tm, ok := asTime("2025-01-01T12:00:00.5+02:00")
+	assert.True(t, ok)
+	assert.Equal(t, 10, tm.UTC().Hour())
+
+	_, ok = asTime("2025-01-01")
+	assert.False(t, ok)
+
+	_, ok = asTime(1.)
+	assert.False(t, ok)
+}